@@ -4,10 +4,16 @@ package maze
 // Actor is something that can move around on the level
 type Actor struct {
 	Character rune       // Display character
+	Color     Color      // Display colour of the character and the path
 	CurrPos   Position   // Current location
 	EndPos    Position   // Destination, if calculated
+	AnyExit   bool       // Head for the nearest exit, EndPos is set to it by the walker
 	Path      []Position // Path, if calculated.
 	PathNav   Walker
+
+	Visits  map[Position]int // Number of steps taken onto each position, see Step
+	Steps   int              // Positions the actor has entered, see Step
+	Stalled int              // Steps in a row that didn't move the actor
 }
 
 // Walker specifies the interface that can be used to walk an Actor through the maze
@@ -22,6 +28,45 @@ func NewActor(c rune, startPos, endPos Position, navigator Walker) *Actor {
 	return &actor
 }
 
+// chooseExit points an actor that heads for any exit at the nearest one. The exit
+// the actor starts on doesn't count.
+func (a *Actor) chooseExit(level *Level) {
+	if !a.AnyExit {
+		return
+	}
+	if exit, ok := level.NearestExit(a.CurrPos); ok {
+		a.EndPos = exit
+	}
+}
+
+// Step moves the actor along with it's walker and counts the visits to the
+// positions it enters. The starting position counts as the first visit, standing
+// still doesn't count. Walkers that add their steps to the path may take several
+// at once, each of them counts.
+func (a *Actor) Step() {
+	if a.Visits == nil {
+		a.Visits = map[Position]int{a.CurrPos: 1}
+	}
+	from, walked := a.CurrPos, len(a.Path)
+	a.PathNav.NextPosition()
+
+	entered := []Position{a.CurrPos}
+	if len(a.Path) > walked {
+		entered = a.Path[walked:]
+	} else if a.CurrPos == from {
+		entered = nil
+	}
+	for _, pos := range entered {
+		a.Visits[pos]++
+	}
+	if len(entered) > 0 {
+		a.Steps += len(entered)
+		a.Stalled = 0
+	} else {
+		a.Stalled++
+	}
+}
+
 // HasFinished returns true if the actor has reached its destination
 func (a Actor) HasFinished() bool {
 	return a.CurrPos == a.EndPos
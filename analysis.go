@@ -0,0 +1,192 @@
+// Package maze, measuring how hard a maze is
+package maze
+
+// Metrics describes the shape of a maze and how hard it is to solve.
+//
+// Tiles are classified by the number of neighbours an actor can step to, following
+// the movement rules of the level: a dead end has one, a corridor tile has two and a
+// junction has three or more. Exits are not counted as dead ends even though they
+// usually have a single neighbour.
+type Metrics struct {
+	Cells     int // Walkable tiles
+	DeadEnds  int // Tiles with a single way out
+	Junctions int // Tiles with three or more ways out
+
+	// Corridors maps corridor length to the number of corridors of that length. A
+	// corridor is a run of tiles with exactly two neighbours between dead ends and
+	// junctions.
+	Corridors map[int]int
+
+	// SolutionLength is the number of steps on the shortest path between start and
+	// end, -1 if the maze can't be solved.
+	SolutionLength int
+
+	// Tortuosity is the cost of the solution divided by the cost of the straight
+	// way from start to end, the Manhattan distance or the octile distance when
+	// the level allows diagonal steps. 1 means the path is a straight line.
+	Tortuosity float64
+
+	// RiverFactor is the average length of the dead end branches, counted from the
+	// dead end to the nearest junction. Mazes with a high river factor have long
+	// meandering side passages, low values mean lots of short dead ends.
+	RiverFactor float64
+
+	// Decisions is the number of junctions on the solution path, places where the
+	// solver has to pick a way.
+	Decisions int
+}
+
+// Analyze measures the maze. The solution metrics are for the path from start to end.
+func Analyze(level Level, start, end Position) Metrics {
+	m := Metrics{Corridors: make(map[int]int), SolutionLength: -1}
+
+	exits := make(map[Position]bool)
+	for _, pos := range level.Exits {
+		exits[pos] = true
+	}
+
+	var deadEnds []Position
+	level.forEachTile(func(pos Position, t Tile) {
+		if !level.IsWalkable(pos) {
+			return
+		}
+		m.Cells++
+		switch degree := level.degree(pos); {
+		case degree == 1 && !exits[pos]:
+			m.DeadEnds++
+			deadEnds = append(deadEnds, pos)
+		case degree >= 3:
+			m.Junctions++
+		}
+	})
+
+	for _, length := range level.corridorLengths() {
+		m.Corridors[length]++
+	}
+
+	if len(deadEnds) > 0 {
+		total := 0
+		for _, pos := range deadEnds {
+			total += level.branchLength(pos)
+		}
+		m.RiverFactor = float64(total) / float64(len(deadEnds))
+	}
+
+	if finish := searchPath(level, start, func(pos Position) bool { return pos == end }); finish != nil {
+		m.SolutionLength = finish.distance
+		if straight := level.estimateCost(end)(start); straight > 0 {
+			m.Tortuosity = float64(finish.cost) / float64(straight)
+		}
+		for n := finish.parent; n != nil && n.parent != nil; n = n.parent {
+			if level.degree(n.pos) >= 3 {
+				m.Decisions++
+			}
+		}
+	}
+
+	return m
+}
+
+// DeadEndDensity returns the share of walkable tiles that are dead ends
+func (m Metrics) DeadEndDensity() float64 {
+	if m.Cells == 0 {
+		return 0
+	}
+	return float64(m.DeadEnds) / float64(m.Cells)
+}
+
+// MeanCorridorLength returns the average length of the corridors
+func (m Metrics) MeanCorridorLength() float64 {
+	count, total := 0, 0
+	for length, n := range m.Corridors {
+		count += n
+		total += length * n
+	}
+	if count == 0 {
+		return 0
+	}
+	return float64(total) / float64(count)
+}
+
+// LongestCorridor returns the length of the longest corridor
+func (m Metrics) LongestCorridor() int {
+	longest := 0
+	for length := range m.Corridors {
+		if length > longest {
+			longest = length
+		}
+	}
+	return longest
+}
+
+// neighbours returns the positions an actor at pos can step to, including the other
+// end of the stairs and, on levels that allow them, the diagonal steps.
+func (level Level) neighbours(pos Position) []Position {
+	var next []Position
+	for _, dir := range level.Directions() {
+		if level.CanStep(pos, dir) {
+			next = append(next, AddDirection(pos, dir))
+		}
+	}
+	return next
+}
+
+// degree returns the number of walkable neighbours of a position
+func (level Level) degree(pos Position) int {
+	return len(level.neighbours(pos))
+}
+
+// corridorLengths finds all the corridors on the level and returns their lengths.
+func (level Level) corridorLengths() []int {
+	var lengths []int
+	visited := make(map[Position]bool)
+
+	level.forEachTile(func(pos Position, t Tile) {
+		if visited[pos] || !level.IsWalkable(pos) || level.degree(pos) != 2 {
+			return
+		}
+
+		// Grow the corridor in both directions from here, a loop of corridor
+		// tiles with no junctions on it ends where it started.
+		visited[pos] = true
+		length := 1
+		for _, next := range level.neighbours(pos) {
+			prev := pos
+			for !visited[next] && level.degree(next) == 2 {
+				visited[next] = true
+				length++
+				prev, next = next, level.nextInCorridor(next, prev)
+			}
+		}
+		lengths = append(lengths, length)
+	})
+	return lengths
+}
+
+// nextInCorridor returns the neighbour of a corridor tile that is not prev
+func (level Level) nextInCorridor(pos, prev Position) Position {
+	for _, next := range level.neighbours(pos) {
+		if next != prev {
+			return next
+		}
+	}
+	return pos
+}
+
+// branchLength returns the number of tiles from a dead end to the nearest junction
+func (level Level) branchLength(deadEnd Position) int {
+	length := 1
+	prev, pos := deadEnd, level.nextInCorridor(deadEnd, deadEnd)
+	for level.degree(pos) == 2 && pos != deadEnd {
+		length++
+		prev, pos = pos, level.nextInCorridor(pos, prev)
+	}
+	return length
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,262 @@
+// Package maze, drawing on a terminal with ANSI escape sequences
+package maze
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ANSIScreen draws on a terminal by writing ANSI escape sequences, and reads the
+// keys from the terminal's input. It moves the cursor only when the cells set
+// aren't next to each other and changes the colours only when they change, so
+// that a DiffRenderer drawing on it writes little more than the changed characters.
+type ANSIScreen struct {
+	out      *bufio.Writer
+	size     func() (int, int)
+	restore  func() // Restores the terminal mode, nil if it wasn't changed
+	row, col int    // Where the cursor is, -1 when not known
+	fg, bg   Color
+	kbEvents KeyboardEventChannel
+}
+
+// NewANSIRenderer creates a renderer that draws on the terminal of stdin and stdout
+// with ANSI escape sequences, without termbox. It works as a replacement for a
+// TermboxRenderer on terminals that termbox doesn't support. Only the changes
+// between the frames are drawn, see DiffRenderer.
+func NewANSIRenderer() *DiffRenderer {
+	return NewDiffRenderer(NewTerminalScreen())
+}
+
+// NewTerminalScreen creates an ANSIScreen on stdin and stdout. On Linux stdin is put
+// in raw mode, so that the keys are read as they are pressed, and the size follows
+// the terminal. Elsewhere, or when stdout isn't a terminal, the size is taken from
+// $COLUMNS and $LINES, 80x24 if they're not set.
+func NewTerminalScreen() *ANSIScreen {
+	// When stdin isn't a terminal the keys come as they are
+	restore, _ := makeRaw(int(os.Stdin.Fd()))
+	s := NewANSIScreen(os.Stdin, os.Stdout, 0, 0)
+	s.restore = restore
+	s.size = func() (int, int) {
+		if width, height, err := windowSize(int(os.Stdout.Fd())); err == nil && width > 0 && height > 0 {
+			return width, height
+		}
+		return envSize()
+	}
+	return s
+}
+
+// envSize returns the size of the terminal from $COLUMNS and $LINES, 80x24 if not set
+func envSize() (int, int) {
+	width, height := 80, 24
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		width = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		height = n
+	}
+	return width, height
+}
+
+// NewANSIScreen creates a screen of the given size writing to out and reading the
+// keys from in, eg. a terminal in raw mode. It switches the terminal to the
+// alternate screen and hides the cursor until Done.
+func NewANSIScreen(in io.Reader, out io.Writer, width, height int) *ANSIScreen {
+	s := &ANSIScreen{out: bufio.NewWriter(out), row: -1, col: -1}
+	s.size = func() (int, int) { return width, height }
+	s.out.WriteString("\x1b[?1049h\x1b[?25l\x1b[0m\x1b[2J")
+	s.out.Flush()
+
+	s.kbEvents = make(KeyboardEventChannel)
+	go s.readKeys(in)
+	return s
+}
+
+// escapeTimeout is how long the rest of an escape sequence split between reads is
+// waited for, before taking what came for keys of their own
+const escapeTimeout = 50 * time.Millisecond
+
+// readKeys reads the keys from in and sends them as keyboard events. When in runs
+// out the channel of the events is closed, there will be no more keys.
+func (s *ANSIScreen) readKeys(in io.Reader) {
+	reads := make(chan []byte)
+	go func() {
+		defer close(reads)
+		buf := make([]byte, 64)
+		for {
+			n, err := in.Read(buf)
+			if n > 0 {
+				reads <- append([]byte(nil), buf[:n]...)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var keys ansiKeyDecoder
+	for {
+		var timeout <-chan time.Time
+		if len(keys.held) > 0 {
+			timeout = time.After(escapeTimeout)
+		}
+		var events []int
+		select {
+		case b, ok := <-reads:
+			if !ok {
+				for _, k := range keys.flush() {
+					s.kbEvents <- k
+				}
+				close(s.kbEvents)
+				return
+			}
+			events = keys.decode(b)
+		case <-timeout:
+			events = keys.flush()
+		}
+		for _, k := range events {
+			s.kbEvents <- k
+		}
+	}
+}
+
+// SetCell puts the character on the screen, it shows after Flush.
+func (s *ANSIScreen) SetCell(col, row int, c rune, fg, bg Color) {
+	if row != s.row || col != s.col {
+		fmt.Fprintf(s.out, "\x1b[%d;%dH", row+1, col+1)
+	}
+	if fg != s.fg || bg != s.bg {
+		fmt.Fprintf(s.out, "\x1b[%d;%dm", ansiColor(fg, 30), ansiColor(bg, 40))
+		s.fg, s.bg = fg, bg
+	}
+	s.out.WriteRune(c)
+	s.row, s.col = row, col+1
+}
+
+// ansiColor returns the SGR parameter of the colour, base is 30 for the foreground
+// and 40 for the background
+func ansiColor(c Color, base int) int {
+	if c == ColorDefault {
+		return base + 9
+	}
+	return base + int(c-ColorBlack)
+}
+
+// Clear blanks the screen.
+func (s *ANSIScreen) Clear() {
+	s.out.WriteString("\x1b[0m\x1b[2J")
+	s.fg, s.bg = ColorDefault, ColorDefault
+	s.row, s.col = -1, -1
+}
+
+// Flush writes out the cells set.
+func (s *ANSIScreen) Flush() {
+	s.out.Flush()
+}
+
+// GetKeyboardEvent returns a channel that can be polled for keyboard events from the terminal.
+func (s *ANSIScreen) GetKeyboardEvent() KeyboardEventChannel {
+	return s.kbEvents
+}
+
+// Size returns the size of the terminal.
+func (s *ANSIScreen) Size() (int, int) {
+	return s.size()
+}
+
+// Done restores the terminal the way it was.
+func (s *ANSIScreen) Done() {
+	s.out.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+	s.out.Flush()
+	if s.restore != nil {
+		s.restore()
+		s.restore = nil
+	}
+}
+
+// ansiKeys are the escape sequences of the special keys, without the ESC
+var ansiKeys = map[string]int{
+	"[A": KBEventUp, "OA": KBEventUp,
+	"[B": KBEventDown, "OB": KBEventDown,
+	"[C": KBEventRight, "OC": KBEventRight,
+	"[D": KBEventLeft, "OD": KBEventLeft,
+	"[H": KBEventUpLeft, "OH": KBEventUpLeft, "[1~": KBEventUpLeft, "[7~": KBEventUpLeft,
+	"[F": KBEventDownLeft, "OF": KBEventDownLeft, "[4~": KBEventDownLeft, "[8~": KBEventDownLeft,
+	"[5~": KBEventUpRight,
+	"[6~": KBEventDownRight,
+}
+
+// maxEscapeLength is the length of the longest escape sequence that is held back
+// waiting for the rest of it, longer ones are unknown keys
+const maxEscapeLength = 8
+
+// ansiKeyDecoder turns the bytes read from the terminal into keyboard events. An
+// escape sequence can be split between reads, especially over the network, so
+// one that is cut short at the end of a read is held until the next one.
+type ansiKeyDecoder struct {
+	held []byte // Start of an escape sequence, waiting for the rest
+}
+
+// decode returns the keyboard events of the bytes read, and of the ones held from
+// the previous read. An ESC that doesn't start an escape sequence is the Esc key.
+func (d *ansiKeyDecoder) decode(read []byte) []int {
+	b := append(d.held, read...)
+	d.held = nil
+	var events []int
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case 0x1b:
+			if i+1 == len(b) {
+				d.held = append([]byte(nil), b[i:]...)
+				return events
+			}
+			if b[i+1] != '[' && b[i+1] != 'O' {
+				events = append(events, KBEventCancel)
+				continue
+			}
+			// The sequence ends in a letter or ~
+			end := i + 2
+			for end < len(b) && end-i < maxEscapeLength && !(b[end] >= 'A' && b[end] <= 'Z' || b[end] == '~') {
+				end++
+			}
+			if end == len(b) && end-i < maxEscapeLength {
+				d.held = append([]byte(nil), b[i:]...)
+				return events
+			}
+			if end == len(b) || end-i == maxEscapeLength {
+				// Too long for a key we know
+				end--
+			}
+			if k, ok := ansiKeys[string(b[i+1:end+1])]; ok {
+				events = append(events, k)
+			} else {
+				events = append(events, KBEventUnknown)
+			}
+			i = end
+		case 0x03:
+			events = append(events, KBEventCancel)
+		case '\t':
+			events = append(events, KBEventNextActor)
+		default:
+			events = append(events, charEvent(rune(b[i])))
+		}
+	}
+	return events
+}
+
+// flush returns the events of the bytes held when the rest of the escape sequence
+// didn't come: a lone ESC is the Esc key, the start of a sequence an unknown key.
+func (d *ansiKeyDecoder) flush() []int {
+	held := d.held
+	d.held = nil
+	switch len(held) {
+	case 0:
+		return nil
+	case 1:
+		return []int{KBEventCancel}
+	}
+	return []int{KBEventUnknown}
+}
@@ -0,0 +1,57 @@
+package maze
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestANSIKeysSplitBetweenReads(t *testing.T) {
+	tests := []struct {
+		name  string
+		reads []string
+		want  []int
+	}{
+		{"whole", []string{"\x1b[A"}, []int{KBEventUp}},
+		{"after the ESC", []string{"\x1b", "[A"}, []int{KBEventUp}},
+		{"after the [", []string{"x\x1b[", "5~"}, []int{charEvent('x'), KBEventUpRight}},
+		{"in three", []string{"\x1b", "O", "D"}, []int{KBEventLeft}},
+		{"two keys", []string{"\x1b[B\x1b", "[C"}, []int{KBEventDown, KBEventRight}},
+		{"esc", []string{"\x1b"}, []int{KBEventCancel}},
+		{"esc and a key", []string{"\x1bq"}, []int{KBEventCancel, charEvent('q')}},
+		{"cut short", []string{"\x1b["}, []int{KBEventUnknown}},
+	}
+	for _, test := range tests {
+		var keys ansiKeyDecoder
+		var got []int
+		for _, read := range test.reads {
+			got = append(got, keys.decode([]byte(read))...)
+		}
+		got = append(got, keys.flush()...)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestANSIControllerEndsWithInput(t *testing.T) {
+	level := readTestLevel(t, crossing)
+	r := NewDiffRenderer(NewANSIScreen(strings.NewReader(""), ioutil.Discard, 80, 24))
+	c := NewController(&level, r)
+	c.Start()
+	done := make(chan bool)
+	go func() {
+		for c.RunLoop() {
+		}
+		c.Done()
+		r.Done()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("controller still running after the input ended")
+	}
+}
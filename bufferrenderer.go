@@ -0,0 +1,190 @@
+// Package maze, rendering into memory for checking what was drawn
+package maze
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Frame is what's drawn between a Reset and a Flush of a BufferRenderer, one slice
+// of cells per line
+type Frame [][]cell
+
+// String returns the frame as text, the lines ending in newlines and without the
+// trailing spaces. Golden files are kept in this form, without the colours.
+func (f Frame) String() string {
+	var b strings.Builder
+	for _, line := range f {
+		text := make([]rune, len(line))
+		for i, k := range line {
+			text[i] = k.c
+		}
+		b.WriteString(strings.TrimRight(string(text), " "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// keyQueueSize is the number of keyboard events a BufferRenderer can queue up
+const keyQueueSize = 64
+
+// BufferRenderer draws in memory instead of a terminal, keeping a snapshot of each
+// frame flushed. It's meant for checking the rendering in tests without a terminal:
+// the keyboard events are queued up with SendKeys and the frames drawn are
+// compared against golden files with CompareGolden.
+type BufferRenderer struct {
+	renderStyle
+	width, height int
+	fg, bg        Color
+	frame         Frame // The frame being drawn
+	frames        []Frame
+	kbEvents      KeyboardEventChannel
+}
+
+// NewBufferRenderer creates a renderer of the given size. What doesn't fit the
+// size is dropped, like on a terminal.
+func NewBufferRenderer(width, height int) *BufferRenderer {
+	return &BufferRenderer{
+		width:    width,
+		height:   height,
+		frame:    Frame{nil},
+		kbEvents: make(KeyboardEventChannel, keyQueueSize),
+	}
+}
+
+// SendKeys queues up keyboard events for the client of the renderer
+func (t *BufferRenderer) SendKeys(events ...int) {
+	for _, e := range events {
+		select {
+		case t.kbEvents <- e:
+		default:
+			panic(fmt.Sprintf("Too many keyboard events queued, at most %d fit", keyQueueSize))
+		}
+	}
+}
+
+// Frames returns the snapshots of the frames flushed so far, the oldest first
+func (t *BufferRenderer) Frames() []Frame {
+	return t.frames
+}
+
+// LastFrame returns the frame flushed last, nil if nothing was flushed yet
+func (t *BufferRenderer) LastFrame() Frame {
+	if len(t.frames) == 0 {
+		return nil
+	}
+	return t.frames[len(t.frames)-1]
+}
+
+// GetKeyboardEvent returns a channel that can be polled for the events queued with SendKeys.
+func (t *BufferRenderer) GetKeyboardEvent() KeyboardEventChannel {
+	return t.kbEvents
+}
+
+// Done is called to shut down the renderer.
+func (t *BufferRenderer) Done() {}
+
+// Size returns the size given to NewBufferRenderer.
+func (t *BufferRenderer) Size() (int, int) {
+	return t.width, t.height
+}
+
+// NextLine starts a new line.
+func (t *BufferRenderer) NextLine() {
+	t.frame = append(t.frame, nil)
+}
+
+// PutChar adds the character to the end of the current line if it fits.
+func (t *BufferRenderer) PutChar(c rune) {
+	line := len(t.frame) - 1
+	if line < t.height && len(t.frame[line]) < t.width {
+		t.frame[line] = append(t.frame[line], cell{c, t.fg, t.bg})
+	}
+}
+
+// SetColor sets the colours of the following characters.
+func (t *BufferRenderer) SetColor(fg, bg Color) {
+	t.fg, t.bg = fg, bg
+}
+
+// Reset starts a new frame from the top left corner.
+func (t *BufferRenderer) Reset() {
+	t.frame = Frame{nil}
+}
+
+// Flush takes a snapshot of the frame drawn since the last Reset. The empty line
+// left by the last NextLine and the lines below the bottom aren't part of it.
+func (t *BufferRenderer) Flush() {
+	lines := t.frame
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > t.height {
+		lines = lines[:t.height]
+	}
+	snapshot := make(Frame, len(lines))
+	for i, line := range lines {
+		snapshot[i] = append([]cell(nil), line...)
+	}
+	t.frames = append(t.frames, snapshot)
+}
+
+// ReadGolden reads a frame from a golden file, in the default colours
+func ReadGolden(path string) (Frame, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Frame
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		var cells []cell
+		for _, c := range strings.TrimSuffix(line, "\n") {
+			cells = append(cells, cell{c: c})
+		}
+		f = append(f, cells)
+	}
+	return f, nil
+}
+
+// WriteGolden writes the frame to a golden file
+func WriteGolden(path string, f Frame) error {
+	return ioutil.WriteFile(path, []byte(f.String()), 0644)
+}
+
+// CompareGolden compares the frame with the one in the golden file, and returns
+// an error telling the first line that differs. With update the golden file is
+// written from the frame instead, for when the rendering changes on purpose or the
+// file doesn't exist yet.
+func CompareGolden(f Frame, path string, update bool) error {
+	if update {
+		return WriteGolden(path, f)
+	}
+	want, err := ReadGolden(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no golden file %s, create it with update", path)
+	} else if err != nil {
+		return err
+	}
+	return DiffFrames(f, want)
+}
+
+// DiffFrames returns an error telling the first line where the frames differ, nil
+// if they're the same. The trailing spaces of the lines and the colours don't count.
+func DiffFrames(got, want Frame) error {
+	if got.String() == want.String() {
+		return nil
+	}
+	gotLines := strings.Split(got.String(), "\n")
+	wantLines := strings.Split(want.String(), "\n")
+	line := 0
+	for line < len(gotLines) && line < len(wantLines) && gotLines[line] == wantLines[line] {
+		line++
+	}
+	return fmt.Errorf("frames differ from line %d, got %d lines, want %d:\n%s\nwant:\n%s",
+		line+1, len(got), len(want), got, want)
+}
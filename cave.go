@@ -0,0 +1,119 @@
+// Package maze, caves grown with a cellular automaton
+package maze
+
+import (
+	"math/rand"
+)
+
+// CaveConfig controls the cave generator. The walls are first scattered at random
+// and then smoothed in rounds, counting the walls among the 8 neighbours of each tile:
+// an empty tile turns into a wall if at least Birth neighbours are walls and a wall
+// stays if at least Survival neighbours are.
+type CaveConfig struct {
+	Fill     float64 // Share of walls to start with
+	Birth    int     // Wall neighbours needed to turn an empty tile into a wall
+	Survival int     // Wall neighbours needed to keep a wall
+	Rounds   int     // Number of smoothing rounds
+	Tunnel   bool    // Connect the separate caves with tunnels instead of filling all but the largest
+}
+
+// DefaultCave is the configuration used by GenerateCave
+var DefaultCave = CaveConfig{Fill: 0.45, Birth: 5, Survival: 4, Rounds: 4}
+
+// GenerateCave generates a cave with the default configuration
+func GenerateCave(width, height int) Level {
+	return GenerateCaveWith(width, height, DefaultCave, DenseStorage)
+}
+
+// GenerateCaveWith generates a cave. The automaton leaves separate pockets of empty
+// space, those are either filled in apart from the largest one or tunneled into it,
+// so that like in GenerateRandomMaze every empty spot can be reached from every
+// other. The entrance is at the top and the exit at the bottom. The tiles are kept
+// in the storage.
+func GenerateCaveWith(width, height int, cfg CaveConfig, storage Storage) Level {
+	level := makeEmptyLevel(width, height, 1, storage)
+	level.forEachTile(func(pos Position, t Tile) {
+		if level.WithinFrame(pos) && rand.Float64() < cfg.Fill {
+			level.setTile(pos, Tile{WallTile, WallBlock})
+		}
+	})
+
+	for round := 0; round < cfg.Rounds; round++ {
+		next := make([]bool, width*height) // Walls of the next round
+		level.forEachTile(func(pos Position, t Tile) {
+			walls := level.wallsAround(pos)
+			if !level.WithinFrame(pos) || (t.tileType == WallTile && walls >= cfg.Survival) ||
+				(t.tileType != WallTile && walls >= cfg.Birth) {
+				next[level.index(pos)] = true
+			}
+		})
+		level.forEachTile(func(pos Position, t Tile) {
+			if next[level.index(pos)] {
+				level.setTile(pos, Tile{WallTile, WallBlock})
+			} else {
+				level.setTile(pos, Tile{EmptyTile, ' '})
+			}
+		})
+	}
+
+	caves := regions(level, level.CanMove)
+	if len(caves) == 0 {
+		// Nothing left, make a cave of the middle of the level
+		caves = [][]Position{{{row: height / 2, col: width / 2}}}
+		level.setTile(caves[0][0], Tile{EmptyTile, ' '})
+	}
+	for _, cave := range caves[1:] {
+		if cfg.Tunnel {
+			level.tunnelTo(cave, caves[0])
+			caves[0] = append(caves[0], cave...)
+		} else {
+			for _, pos := range cave {
+				level.setTile(pos, Tile{WallTile, WallBlock})
+			}
+		}
+	}
+
+	// The exits are ploughed straight in from above the topmost spot and below the
+	// bottom one, the cave is the opening they always reach so they can't fail
+	top, bottom := caves[0][0], caves[0][0]
+	for _, pos := range caves[0] {
+		if pos.row < top.row || (pos.row == top.row && pos.col < top.col) {
+			top = pos
+		}
+		if pos.row > bottom.row || (pos.row == bottom.row && pos.col > bottom.col) {
+			bottom = pos
+		}
+	}
+	level.CreateHorizontalExit(Position{row: 0, col: top.col})
+	level.CreateHorizontalExit(Position{row: height - 1, col: bottom.col})
+	return level
+}
+
+// wallsAround counts the walls among the 8 neighbours of the position, anything
+// outside the level counts as a wall
+func (level Level) wallsAround(pos Position) int {
+	walls := 0
+	for row := pos.row - 1; row <= pos.row+1; row++ {
+		for col := pos.col - 1; col <= pos.col+1; col++ {
+			near := Position{row: row, col: col, floor: pos.floor}
+			if near != pos && (!level.WithinBounds(near) || level.tile(near).tileType == WallTile) {
+				walls++
+			}
+		}
+	}
+	return walls
+}
+
+// tunnelTo digs a corridor from the cave to the closest spot of the other one
+func (level *Level) tunnelTo(cave, other []Position) {
+	from, to := cave[0], other[0]
+	best := -1
+	for _, a := range cave {
+		for _, b := range other {
+			if d := abs(a.row-b.row) + abs(a.col-b.col); best < 0 || d < best {
+				from, to, best = a, b, d
+			}
+		}
+	}
+	level.carveCorridor(from, to)
+}
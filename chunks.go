@@ -0,0 +1,212 @@
+// Package maze, huge levels generated in chunks
+package maze
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+const (
+	// ChunkSize is the width and height of the chunks of a chunked level
+	ChunkSize = 32
+
+	// maxChunks is the number of chunks kept in memory, the rest are generated again when needed
+	maxChunks = 1024
+
+	// maxEdits is the number of changed tiles a chunked level keeps
+	maxEdits = 1 << 16
+)
+
+// errChunked is returned when asked to go through every tile of a chunked level
+var errChunked = errors.New("the level is generated in chunks and too large to go through whole, only the part around a position can be drawn")
+
+// chunk is a square piece of a chunked level. The chunk owns the wall along it's
+// top and left edges, the ones on the bottom and right belong to the neighbours.
+type chunk struct {
+	key   [2]int
+	tiles [ChunkSize][ChunkSize]Tile
+}
+
+// chunkedTiles generates the tiles of a level a chunk at a time, when they're first
+// looked at. A chunk is generated from the seed of the level and the position of the
+// chunk only, so when memory runs short the chunk used the longest time ago is
+// forgotten and generated again when needed. Changes to the tiles are kept
+// separately and applied to the chunks as they are generated, so they survive that.
+type chunkedTiles struct {
+	seed       int64
+	wide, high int                          // Number of chunks across and down
+	chunks     map[[2]int]*list.Element     // The chunks in memory, elements of lru
+	lru        *list.List                   // Of *chunk, the most recently used first
+	edits      map[[2]int]map[Position]Tile // Changed tiles by the chunk they're in
+	editCount  int
+	lastKey    [2]int // The chunk looked at last, most lookups are close to the previous one
+	lastChunk  *chunk
+}
+
+// NewChunkedLevel creates a maze level that is generated lazily in chunks, so it can
+// be far larger than what would fit in memory. The width and height are rounded up
+// to whole chunks, plus the wall on the right and bottom edges. The maze is the same
+// for the same seed and, like GenerateRandomMaze, every empty spot can be reached
+// from every other one. There's an entrance in the top left and an exit in the
+// bottom right.
+//
+// Going through all the tiles of the level would take forever, so it can't be drawn
+// whole or written out: Render and WriteLevel fail, RenderAround draws a part of it.
+func NewChunkedLevel(width, height int, seed int64) Level {
+	tiles := &chunkedTiles{
+		seed:   seed,
+		wide:   chunksFor(width),
+		high:   chunksFor(height),
+		chunks: make(map[[2]int]*list.Element),
+		lru:    list.New(),
+		edits:  make(map[[2]int]map[Position]Tile),
+	}
+	level := Level{width: tiles.wide*ChunkSize + 1, height: tiles.high*ChunkSize + 1, floors: 1, tiles: tiles}
+	// Right next to the exits are the corner cells of the maze, which every chunk
+	// has open, so the exits can't fail
+	level.CreateHorizontalExit(Position{row: 0, col: 1})
+	level.CreateHorizontalExit(Position{row: level.height - 1, col: level.width - 2})
+	return level
+}
+
+// Chunked tells if the level is generated in chunks, see NewChunkedLevel
+func (level Level) Chunked() bool {
+	_, ok := level.tiles.(*chunkedTiles)
+	return ok
+}
+
+// chunksFor returns the number of chunks needed to cover the length in tiles
+func chunksFor(length int) int {
+	n := (length - 1 + ChunkSize - 1) / ChunkSize
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// chunkOf returns the row and column of the chunk that the position is in
+func chunkOf(pos Position) [2]int {
+	return [2]int{pos.row / ChunkSize, pos.col / ChunkSize}
+}
+
+// Tile returns the tile at the position, generating it's chunk if needed
+func (tiles *chunkedTiles) Tile(pos Position) Tile {
+	key := chunkOf(pos)
+	if key[0] >= tiles.high || key[1] >= tiles.wide {
+		// The wall on the bottom and right edges of the level
+		if t, ok := tiles.edits[key][pos]; ok {
+			return t
+		}
+		return Tile{WallTile, WallBlock}
+	}
+	return tiles.chunk(key).tiles[pos.row%ChunkSize][pos.col%ChunkSize]
+}
+
+// SetTile replaces the tile at the position. Panics if more than maxEdits tiles
+// are changed, a chunked level isn't meant for building on.
+func (tiles *chunkedTiles) SetTile(pos Position, t Tile) {
+	key := chunkOf(pos)
+	if tiles.edits[key] == nil {
+		tiles.edits[key] = make(map[Position]Tile)
+	}
+	if _, ok := tiles.edits[key][pos]; !ok {
+		if tiles.editCount == maxEdits {
+			panic(fmt.Sprintf("Chunked level can't keep more than %d changed tiles!", maxEdits))
+		}
+		tiles.editCount++
+	}
+	tiles.edits[key][pos] = t
+	if e, ok := tiles.chunks[key]; ok {
+		e.Value.(*chunk).tiles[pos.row%ChunkSize][pos.col%ChunkSize] = t
+	}
+}
+
+// chunk returns the chunk with the given row and column, generating it if it's not
+// in memory
+func (tiles *chunkedTiles) chunk(key [2]int) *chunk {
+	if tiles.lastChunk != nil && key == tiles.lastKey {
+		return tiles.lastChunk
+	}
+
+	var c *chunk
+	if e, ok := tiles.chunks[key]; ok {
+		tiles.lru.MoveToFront(e)
+		c = e.Value.(*chunk)
+	} else {
+		if tiles.lru.Len() >= maxChunks {
+			oldest := tiles.lru.Remove(tiles.lru.Back()).(*chunk)
+			delete(tiles.chunks, oldest.key)
+		}
+		c = generateChunk(tiles.seed, key[0], key[1])
+		for pos, t := range tiles.edits[key] {
+			c.tiles[pos.row%ChunkSize][pos.col%ChunkSize] = t
+		}
+		tiles.chunks[key] = tiles.lru.PushFront(c)
+	}
+	tiles.lastKey, tiles.lastChunk = key, c
+	return c
+}
+
+// chunkSeed mixes the level seed with the position of the chunk (splitmix64)
+func chunkSeed(seed int64, row, col int) int64 {
+	z := uint64(seed) + uint64(row)*0x9e3779b97f4a7c15 + uint64(col)*0xc2b2ae3d27d4eb4f
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
+// generateChunk generates the maze of a chunk. The chunk is a grid of cells on the
+// odd rows and columns, with walls between them. The cells are joined into a maze
+// with a randomized depth first search, so the chunk is connected within itself,
+// and then the walls on the top and left edges are opened in a place or two to join
+// the chunk with the ones above it and to the left. Each chunk being connected to
+// it's neighbours, the whole level is connected.
+func generateChunk(seed int64, chunkRow, chunkCol int) *chunk {
+	rng := rand.New(rand.NewSource(chunkSeed(seed, chunkRow, chunkCol)))
+	c := &chunk{key: [2]int{chunkRow, chunkCol}}
+	for row := range c.tiles {
+		for col := range c.tiles[row] {
+			c.tiles[row][col] = Tile{WallTile, WallBlock}
+		}
+	}
+
+	const cells = ChunkSize / 2
+	visited := [cells][cells]bool{}
+	stack := [][2]int{{rng.Intn(cells), rng.Intn(cells)}}
+	visited[stack[0][0]][stack[0][1]] = true
+	c.tiles[2*stack[0][0]+1][2*stack[0][1]+1] = Tile{EmptyTile, ' '}
+	for len(stack) > 0 {
+		cell := stack[len(stack)-1]
+		var next [][2]int
+		for _, dir := range ValidDirections {
+			r, k := cell[0]+dir.yd, cell[1]+dir.xd
+			if r >= 0 && k >= 0 && r < cells && k < cells && !visited[r][k] {
+				next = append(next, [2]int{r, k})
+			}
+		}
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		n := next[rng.Intn(len(next))]
+		visited[n[0]][n[1]] = true
+		c.tiles[2*n[0]+1][2*n[1]+1] = Tile{EmptyTile, ' '}
+		c.tiles[cell[0]+n[0]+1][cell[1]+n[1]+1] = Tile{EmptyTile, ' '}
+		stack = append(stack, n)
+	}
+
+	// Open the seams, the level frame stays closed
+	if chunkRow > 0 {
+		for i := 1 + rng.Intn(2); i > 0; i-- {
+			c.tiles[0][2*rng.Intn(cells)+1] = Tile{EmptyTile, ' '}
+		}
+	}
+	if chunkCol > 0 {
+		for i := 1 + rng.Intn(2); i > 0; i-- {
+			c.tiles[2*rng.Intn(cells)+1][0] = Tile{EmptyTile, ' '}
+		}
+	}
+	return c
+}
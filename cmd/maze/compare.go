@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mpihlak/maze"
+)
+
+// runCompare races walkers side by side on copies of the same maze, and prints
+// the standings once they are done.
+func runCompare(args []string) error {
+	var opts options
+	fs := newFlagSet("compare", "[options] [LEVEL]\n\nLEVEL is a level file to compare the walkers on, - for stdin.", &opts,
+		concat(levelOptions, walkerOptions, terminalOptions)...)
+	fs.Lookup("walker").Usage = "comma separated walkers to compare: " + walkerNames()
+	fs.Lookup("walker").DefValue = "shortestpath,shortestline,wallfollower"
+	opts.walker = fs.Lookup("walker").DefValue
+	opts.parse(fs, args)
+	if fs.NArg() > 0 {
+		opts.input = fs.Arg(0)
+	}
+
+	results, err := compareWalkers(&opts)
+	if err != nil {
+		return err
+	}
+	for _, line := range results {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// compareWalkers runs the comparison on the terminal and returns the results
+func compareWalkers(opts *options) ([]string, error) {
+	var walkers []maze.Walker
+	for _, name := range strings.Split(opts.walker, ",") {
+		walker, err := maze.NewWalker(name)
+		if err != nil {
+			return nil, err
+		}
+		walkers = append(walkers, walker)
+	}
+
+	render, err := opts.newTerminal()
+	if err != nil {
+		return nil, err
+	}
+	defer render.Done()
+	opts.fitTerminal(render)
+	if !opts.explicit["width"] {
+		// The panes are side by side, one column apart
+		opts.width = (opts.width+1)/len(walkers) - 1
+	}
+
+	level, err := opts.levelOrGenerate()
+	if err != nil {
+		return nil, err
+	}
+	if len(level.Actors) == 0 && len(level.Exits) >= 2 {
+		// The walkers set off like this actor, heading for the --nearest exit or the second one
+		actor := maze.NewActor('@', level.Exits[0], level.Exits[1], nil)
+		actor.AnyExit = opts.nearest
+		level.AddActor(actor)
+	}
+
+	comparison, err := maze.NewComparison(level, walkers, nil, render)
+	if err != nil {
+		return nil, err
+	}
+	comparison.Start()
+	for comparison.RunLoop() {
+		// RunLoop takes care of rendering and keyboard events.
+	}
+	comparison.Done()
+	return comparison.Results(), nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// runGenerate generates a maze and writes it as a level file
+func runGenerate(args []string) error {
+	var opts options
+	fs := newFlagSet("generate", "[options]", &opts, concat(generateOptions, []string{"grid", "format", "output"})...)
+	opts.parse(fs, args)
+	if opts.isGridMaze() {
+		return opts.writeGridMaze(false)
+	}
+
+	level, err := opts.generate()
+	if err != nil {
+		return err
+	}
+	if opts.attempts > 1 {
+		fmt.Fprintf(os.Stderr, "Seed %d met the difficulty target after %d attempts\n", opts.seed, opts.attempts)
+	}
+
+	out, closeOutput, err := opts.createOutput()
+	if err != nil {
+		return err
+	}
+	if err := opts.writeLevel(out, level, fmt.Sprintf("Seed=%v", opts.seed), "ascii"); err != nil {
+		closeOutput()
+		return err
+	}
+	return closeOutput()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mpihlak/maze"
+)
+
+// gridScales is the number of text rows per grid unit when drawing the grids as text
+var gridScales = map[string]float64{
+	"square":   2,
+	"hex":      2,
+	"triangle": 2.5,
+	"polar":    2,
+}
+
+// newGrid creates the --grid grid, sized by --width and --height in cells. Polar
+// grids have --height rings.
+func (opts *options) newGrid() (maze.Grid, error) {
+	if opts.width < 1 || opts.height < 1 {
+		return nil, fmt.Errorf("grid must be at least 1x1, got %dx%d", opts.width, opts.height)
+	}
+	switch opts.grid {
+	case "square":
+		return maze.SquareGrid{Rows: opts.height, Cols: opts.width}, nil
+	case "hex":
+		return maze.HexGrid{Rows: opts.height, Cols: opts.width}, nil
+	case "triangle":
+		return maze.TriangleGrid{Rows: opts.height, Cols: opts.width}, nil
+	case "polar":
+		return maze.NewPolarGrid(opts.height), nil
+	}
+	return nil, fmt.Errorf("unknown grid %q", opts.grid)
+}
+
+// isGridMaze tells if --grid selects a cell grid rather than the default tile level
+func (opts *options) isGridMaze() bool {
+	return opts.grid != "" && opts.grid != "tiles"
+}
+
+// gridMazeOptions are the options that grid mazes take, the rest only work on tile
+// levels
+var gridMazeOptions = map[string]bool{"seed": true, "width": true, "height": true, "grid": true, "format": true, "output": true}
+
+// writeGridMaze generates a maze on the --grid grid, solves it from the first cell
+// to the last if solve is set, and writes it in the --format format. The walkers,
+// level files and the rest of the tile level options don't work on the grids, so
+// giving them is an error.
+func (opts *options) writeGridMaze(solve bool) error {
+	var unsupported []string
+	for name := range opts.explicit {
+		if !gridMazeOptions[name] {
+			unsupported = append(unsupported, "--"+name)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("--grid %s doesn't support %s, only tile levels do", opts.grid, strings.Join(unsupported, ", "))
+	}
+
+	grid, err := opts.newGrid()
+	if err != nil {
+		return err
+	}
+	opts.seedRandom()
+	m := maze.GenerateGridMaze(grid)
+
+	var path []maze.Cell
+	if solve {
+		path = m.Solve(0, maze.Cell(grid.Size()-1))
+	}
+
+	out, closeOutput, err := opts.createOutput()
+	if err != nil {
+		return err
+	}
+	switch opts.format {
+	case "", "text":
+		banner := fmt.Sprintf("Seed=%v", opts.seed)
+		if solve {
+			banner += fmt.Sprintf(" Path length=%v.", len(path))
+		}
+		maze.RenderGrid(m, path, banner, gridScales[opts.grid], maze.NewStreamRendererTo(out))
+	case "svg":
+		err = maze.WriteGridSVG(out, m, path)
+	default:
+		err = fmt.Errorf("can't write %s grid mazes as %q, use text or svg", opts.grid, opts.format)
+	}
+	if err != nil {
+		closeOutput()
+		return err
+	}
+	return closeOutput()
+}
+
+// writeLevel writes the level in the --format format, def is the default format
+func (opts *options) writeLevel(w io.Writer, level maze.Level, banner, def string) error {
+	format := opts.format
+	if format == "" {
+		format = def
+	}
+	write, ok := formats[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, choose one of: %s", format, formatNames())
+	}
+	return write(w, level, banner, opts)
+}
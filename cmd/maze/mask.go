@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mpihlak/maze"
+)
+
+// textMaskScale is the height in tiles of a dot in the font of text masks
+const textMaskScale = 3
+
+// loadMask creates the mask given with --mask: "circle", "text:SOME TEXT" or the
+// name of an ASCII art or PNG file. The circle fills the maze, text and files are
+// scaled to --width and --height only if they're given.
+func (opts *options) loadMask() (*maze.Mask, error) {
+	if opts.mask == "circle" {
+		return maze.CircleMask(opts.width-2, opts.height-2), nil
+	}
+
+	var mask *maze.Mask
+	if strings.HasPrefix(opts.mask, "text:") {
+		mask = maze.TextMask(strings.TrimPrefix(opts.mask, "text:"), textMaskScale)
+	} else {
+		f, err := os.Open(opts.mask)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		if strings.HasSuffix(strings.ToLower(opts.mask), ".png") {
+			mask, err = maze.ReadPNGMask(f)
+		} else {
+			mask, err = maze.ReadMask(f)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", opts.mask, err)
+		}
+	}
+
+	width, height := mask.Size()
+	if opts.explicit["width"] {
+		width = opts.width - 2
+	}
+	if opts.explicit["height"] {
+		height = opts.height - 2
+	}
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("the mask %q is empty", opts.mask)
+	}
+	return mask.Scale(width, height), nil
+}
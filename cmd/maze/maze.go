@@ -0,0 +1,533 @@
+// Command maze generates, solves, races and renders mazes.
+//
+// Usage:
+//
+//	maze <command> [options]
+//
+// Run "maze help" for the list of commands and "maze <command> -h" for the
+// options of a single command.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mpihlak/maze"
+)
+
+// command is a maze subcommand
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"generate": {"generate a random maze and write it as a level file", runGenerate},
+	"solve":    {"solve a maze with a walker and print the path", runSolve},
+	"race":     {"race walkers through a maze on the terminal", runRace},
+	"play":     {"walk through a maze yourself with the arrow keys", runPlay},
+	"render":   {"render a level file as text", runRender},
+	"convert":  {"convert a level between formats", runConvert},
+	"stats":    {"print metrics that describe how hard a maze is", runStats},
+	"compare":  {"race walkers side by side on copies of the same maze", runCompare},
+	"walkers":  {"list the walkers and their settings", runWalkers},
+}
+
+// generator makes a maze of the size, with the tiles kept in the storage
+type generator func(width, height int, storage maze.Storage) (maze.Level, error)
+
+// generators maps the --algorithm names to maze generators
+var generators = map[string]generator{
+	"backtrack": func(width, height int, storage maze.Storage) (maze.Level, error) {
+		return maze.GenerateMultiFloorMaze(width, height, 1, storage)
+	},
+	"cave": func(width, height int, storage maze.Storage) (maze.Level, error) {
+		return maze.GenerateCaveWith(width, height, maze.DefaultCave, storage), nil
+	},
+	"dungeon": func(width, height int, storage maze.Storage) (maze.Level, error) {
+		return maze.GenerateDungeonWith(width, height, maze.DefaultDungeon, storage), nil
+	},
+	"chunked": func(width, height int, storage maze.Storage) (maze.Level, error) {
+		if storage != maze.DenseStorage {
+			return maze.Level{}, fmt.Errorf("chunked mazes have a storage of their own, there's no choosing it")
+		}
+		return maze.NewChunkedLevel(width, height, rand.Int63()), nil
+	},
+
+	"binarytree": edgeGenerator("binarytree"),
+	"sidewinder": edgeGenerator("sidewinder"),
+	"prim":       edgeGenerator("prim"),
+	"kruskal":    edgeGenerator("kruskal"),
+}
+
+// edgeGenerator returns a generator for one of the maze.EdgeGenerators
+func edgeGenerator(name string) generator {
+	return func(width, height int, storage maze.Storage) (maze.Level, error) {
+		return maze.GenerateEdgeLevel(name, width, height, storage)
+	}
+}
+
+// options holds the flags shared by the subcommands. Not every command uses
+// all of them, but when they are used they mean the same thing everywhere.
+type options struct {
+	seed      int64
+	width     int
+	height    int
+	floors    int
+	algorithm string
+	walker    string
+	input     string
+	output    string
+	grid      string
+	format    string
+	diagonal  bool
+	corners   string
+	mask      string
+	void      bool
+	exits     string
+	nearest   bool
+	cave      maze.CaveConfig
+	storage   string
+	walls     string
+	heatmap   bool
+	search    bool
+	terminal  string
+	status    bool
+
+	difficulty string          // Difficulty preset
+	target     maze.Difficulty // Difficulty target, adjusted by the preset
+	attempts   int             // Maximum attempts to hit the target, then the attempts taken
+
+	explicit map[string]bool // Options given on the command line
+}
+
+// Groups of options that go together, see newFlagSet
+var (
+	// Generating a maze
+	generateOptions = []string{"seed", "width", "height", "floors", "mask", "exits", "algorithm", "cave", "difficulty", "storage"}
+	// Generating a maze or reading it from a level file, for walking it
+	levelOptions = concat(generateOptions, []string{"input", "movement"})
+	// Choosing the walkers of the actors
+	walkerOptions = []string{"walker", "nearest"}
+	// Drawing the maze on the terminal
+	terminalOptions = []string{"walls", "heatmap", "terminal"}
+	// Running the actors with the Controller
+	controllerOptions = []string{"search", "status"}
+)
+
+// concat joins option groups
+func concat(groups ...[]string) []string {
+	var names []string
+	for _, g := range groups {
+		names = append(names, g...)
+	}
+	return names
+}
+
+// newFlagSet creates a flag set for the command and registers the named
+// shared options on it.
+func newFlagSet(name, usage string, opts *options, names ...string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: maze %s %s\n\nOptions:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	for _, n := range names {
+		switch n {
+		case "seed":
+			fs.Int64Var(&opts.seed, "seed", 0, "random seed, 0 picks one from the clock")
+		case "width":
+			fs.IntVar(&opts.width, "width", 40, "maze width in tiles")
+		case "height":
+			fs.IntVar(&opts.height, "height", 20, "maze height in tiles")
+		case "floors":
+			fs.IntVar(&opts.floors, "floors", 1, "number of floors, connected with stairs")
+		case "algorithm":
+			fs.StringVar(&opts.algorithm, "algorithm", "backtrack", "maze generator: "+generatorNames())
+		case "walker":
+			fs.StringVar(&opts.walker, "walker", maze.DefaultWalker, "walker, or a list of glyph=walker per actor, see maze walkers for the settings: "+walkerNames())
+		case "input":
+			fs.StringVar(&opts.input, "input", "", "level file to read, - for stdin")
+		case "output":
+			fs.StringVar(&opts.output, "output", "", "file to write, stdout if empty")
+		case "grid":
+			fs.StringVar(&opts.grid, "grid", "tiles", "grid the maze is carved into: tiles, square, hex, triangle, polar")
+		case "format":
+			fs.StringVar(&opts.format, "format", "", "output format: "+formatNames())
+		case "mask":
+			fs.StringVar(&opts.mask, "mask", "", "shape of the maze: circle, text:TEXT or an ASCII art or PNG file")
+			fs.BoolVar(&opts.void, "void", false, "leave the tiles outside the mask empty instead of solid")
+		case "cave":
+			def := maze.DefaultCave
+			fs.Float64Var(&opts.cave.Fill, "fill", def.Fill, "cave algorithm: share of walls to start with")
+			fs.IntVar(&opts.cave.Birth, "birth", def.Birth, "cave algorithm: wall neighbours that turn an empty tile into a wall")
+			fs.IntVar(&opts.cave.Survival, "survival", def.Survival, "cave algorithm: wall neighbours that keep a wall")
+			fs.IntVar(&opts.cave.Rounds, "rounds", def.Rounds, "cave algorithm: smoothing rounds")
+			fs.BoolVar(&opts.cave.Tunnel, "tunnel", def.Tunnel, "cave algorithm: tunnel between caves instead of keeping the largest")
+		case "exits":
+			fs.StringVar(&opts.exits, "exits", "", "exits of the maze: a count of random exits, optionally on some edges (3:top,left), or row,col;row,col")
+		case "nearest":
+			fs.BoolVar(&opts.nearest, "nearest", false, "actors head for the nearest exit instead of a fixed one")
+		case "storage":
+			fs.StringVar(&opts.storage, "storage", maze.DenseStorage.String(), "how the tiles are stored: "+strings.Join(maze.StorageNames(), ", ")+", packed takes less memory")
+		case "walls":
+			fs.StringVar(&opts.walls, "walls", "block", "how the walls are drawn: "+strings.Join(maze.WallStyleNames(), ", "))
+		case "heatmap":
+			fs.BoolVar(&opts.heatmap, "heatmap", false, "draw how many times the actors visited each tile instead of their paths, 'h' toggles it in race and play")
+		case "search":
+			fs.BoolVar(&opts.search, "show-search", false, "show the walkers searching for their paths before they set off, step by step")
+		case "status":
+			fs.BoolVar(&opts.status, "status", true, "show the state of each actor on the side, 's' toggles it")
+		case "terminal":
+			fs.StringVar(&opts.terminal, "terminal", "termbox", "how the terminal is driven: termbox, or ansi for plain escape sequences")
+		case "movement":
+			fs.BoolVar(&opts.diagonal, "diagonal", false, "allow diagonal steps")
+			fs.StringVar(&opts.corners, "corners", "none", "diagonal steps past wall corners: none, cut (past one wall), squeeze (between two walls)")
+		case "difficulty":
+			fs.StringVar(&opts.difficulty, "difficulty", "", "generate a maze of this difficulty: "+strings.Join(maze.DifficultyPresets, ", "))
+			fs.IntVar(&opts.target.MinSolutionLength, "min-solution-length", 0, "generate a maze with at least this long a solution")
+			fs.Float64Var(&opts.target.MinDeadEndDensity, "min-dead-end-density", 0, "generate a maze with at least this share of dead end tiles")
+			fs.IntVar(&opts.target.MinDecisions, "min-decisions", 0, "generate a maze with at least this many junctions on the solution")
+			fs.IntVar(&opts.attempts, "attempts", 1000, "give up if no maze hits the difficulty target in this many attempts")
+		default:
+			panic("unknown option " + n)
+		}
+	}
+	return fs
+}
+
+// parse parses the command line and records which options were given
+func (opts *options) parse(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	opts.explicit = make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { opts.explicit[f.Name] = true })
+}
+
+// generatorNames lists the --algorithm choices
+func generatorNames() string {
+	var keys []string
+	for k := range generators {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
+
+// walkerNames lists the --walker choices
+func walkerNames() string {
+	return strings.Join(maze.WalkerNames(), ", ")
+}
+
+// seedRandom seeds the random generator and returns the seed used
+func (opts *options) seedRandom() int64 {
+	if opts.seed == 0 {
+		opts.seed = time.Now().UTC().UnixNano()
+	}
+	rand.Seed(opts.seed)
+	return opts.seed
+}
+
+// walkerFor creates the walker selected with --walker for the actor with the
+// given glyph. The option is a comma separated list of walker names, either
+// plain (the default for all actors) or prefixed with a glyph, eg.
+// "shortestpath,&=shortestline". The walkers can have settings, see NewWalker.
+// Returns nil if no walker is given for the actor.
+func (opts *options) walkerFor(glyph rune) (maze.Walker, error) {
+	name := ""
+	for _, item := range strings.Split(opts.walker, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if g := []rune(kv[0]); len(kv) == 2 && len(g) == 1 {
+			if g[0] == glyph {
+				name = kv[1]
+				break
+			}
+		} else if name == "" {
+			name = item
+		}
+	}
+	if name == "" {
+		return nil, nil
+	}
+	return maze.NewWalker(name)
+}
+
+// assignWalkers gives the actors loaded from a level file the walkers selected
+// with --walker. Without the option the actors keep the walkers from the file.
+func (opts *options) assignWalkers(level *maze.Level) error {
+	if !opts.explicit["walker"] {
+		return nil
+	}
+	for _, actor := range level.Actors {
+		walker, err := opts.walkerFor(actor.Character)
+		if err != nil {
+			return err
+		}
+		if walker != nil {
+			actor.PathNav = walker
+		}
+	}
+	return nil
+}
+
+// newActor creates an actor with the walker selected for it with --walker,
+// the default walker if there's none.
+func (opts *options) newActor(c rune, startPos, endPos maze.Position) (*maze.Actor, error) {
+	walker, err := opts.walkerFor(c)
+	if err == nil && walker == nil {
+		walker, err = maze.NewWalker(maze.DefaultWalker)
+	}
+	if err != nil {
+		return nil, err
+	}
+	actor := maze.NewActor(c, startPos, endPos, walker)
+	actor.AnyExit = opts.nearest
+	return actor, nil
+}
+
+// generate creates a new maze with the selected algorithm and dimensions. If a
+// difficulty target is given, mazes are generated until one meets it.
+func (opts *options) generate() (maze.Level, error) {
+	gen, ok := generators[opts.algorithm]
+	if !ok {
+		return maze.Level{}, fmt.Errorf("unknown algorithm %q, choose one of: %s", opts.algorithm, generatorNames())
+	}
+	if opts.width < 3 || opts.height < 3 {
+		return maze.Level{}, fmt.Errorf("maze must be at least 3x3, got %dx%d", opts.width, opts.height)
+	}
+	storage, err := opts.tileStorage()
+	if err != nil {
+		return maze.Level{}, err
+	}
+	generate := func() (maze.Level, error) { return gen(opts.width, opts.height, storage) }
+	if opts.algorithm == "cave" {
+		generate = func() (maze.Level, error) {
+			return maze.GenerateCaveWith(opts.width, opts.height, opts.cave, storage), nil
+		}
+	}
+	if opts.floors > 1 || opts.exits != "" {
+		if opts.algorithm != "backtrack" {
+			return maze.Level{}, fmt.Errorf("only the backtrack algorithm makes mazes with several floors or custom exits")
+		}
+		if opts.floors > 1 && (opts.width < 5 || opts.height < 5) {
+			return maze.Level{}, fmt.Errorf("maze with several floors must be at least 5x5, got %dx%d", opts.width, opts.height)
+		}
+		var spec maze.ExitSpec
+		if opts.exits != "" {
+			var err error
+			if spec, err = maze.ParseExitSpec(opts.exits); err != nil {
+				return maze.Level{}, err
+			}
+		}
+		floors := opts.floors
+		if floors < 1 {
+			floors = 1
+		}
+		generate = func() (maze.Level, error) {
+			return maze.GenerateMazeWithExits(opts.width, opts.height, floors, spec, storage)
+		}
+	}
+	if opts.mask != "" {
+		if opts.algorithm != "backtrack" || opts.floors > 1 || opts.exits != "" {
+			return maze.Level{}, fmt.Errorf("only the backtrack algorithm makes masked mazes, on one floor with the exits on the mask")
+		}
+		mask, err := opts.loadMask()
+		if err != nil {
+			return maze.Level{}, err
+		}
+		width, height := mask.Size()
+		opts.width, opts.height = width+2, height+2
+		generate = func() (maze.Level, error) { return maze.GenerateMaskedMaze(mask, opts.void, storage) }
+	}
+	opts.seedRandom()
+
+	target := opts.target
+	if opts.difficulty != "" {
+		preset, err := maze.DifficultyPreset(opts.difficulty, opts.width, opts.height)
+		if err != nil {
+			return maze.Level{}, err
+		}
+		// Explicit targets tighten the preset
+		if target.MinSolutionLength < preset.MinSolutionLength {
+			target.MinSolutionLength = preset.MinSolutionLength
+		}
+		if target.MinDeadEndDensity < preset.MinDeadEndDensity {
+			target.MinDeadEndDensity = preset.MinDeadEndDensity
+		}
+		if target.MinDecisions < preset.MinDecisions {
+			target.MinDecisions = preset.MinDecisions
+		}
+		target.MaxSolutionLength = preset.MaxSolutionLength
+		target.MaxDecisions = preset.MaxDecisions
+	}
+	if target == (maze.Difficulty{}) {
+		opts.attempts = 1
+		return generate()
+	}
+	if opts.algorithm == "chunked" {
+		return maze.Level{}, fmt.Errorf("chunked mazes are too large to measure for a difficulty target")
+	}
+
+	result, err := maze.GenerateWithDifficulty(generate, target, opts.seed, opts.attempts)
+	if err != nil {
+		return maze.Level{}, err
+	}
+	opts.seed = result.Seed
+	opts.attempts = result.Attempts
+	return result.Level, nil
+}
+
+// tileStorage returns the --storage for the levels generated or read
+func (opts *options) tileStorage() (maze.Storage, error) {
+	if opts.storage == "" {
+		return maze.DenseStorage, nil
+	}
+	return maze.ParseStorage(opts.storage)
+}
+
+// styleWalls draws the walls on the renderer in the --walls style. The --heatmap
+// needs a style that draws a character per tile.
+func (opts *options) styleWalls(r interface{ SetWallStyle(maze.WallStyle) }) error {
+	if opts.walls == "" {
+		return nil
+	}
+	style, err := maze.ParseWallStyle(opts.walls)
+	if err != nil {
+		return err
+	}
+	if opts.heatmap && (style.RowsPerLine() > 1 || style.ColsPerChar() > 1) {
+		return fmt.Errorf("the heatmap can't be drawn with %s walls, they draw several tiles per character", opts.walls)
+	}
+	r.SetWallStyle(style)
+	return nil
+}
+
+// newTerminal opens the --terminal for drawing the maze with the --walls and
+// --heatmap options. Only the changes between the frames are drawn.
+func (opts *options) newTerminal() (*maze.DiffRenderer, error) {
+	var render *maze.DiffRenderer
+	switch opts.terminal {
+	case "termbox", "":
+		render = maze.NewDiffRenderer(maze.NewTermboxRenderer())
+	case "ansi":
+		render = maze.NewANSIRenderer()
+	default:
+		return nil, fmt.Errorf("unknown terminal %q, choose termbox or ansi", opts.terminal)
+	}
+
+	if err := opts.styleWalls(render); err != nil {
+		render.Done()
+		return nil, err
+	}
+	render.SetHeatmap(opts.heatmap)
+	return render, nil
+}
+
+// loadLevel reads a level file into the storage, "-" reads the level from stdin
+func loadLevel(path string, storage maze.Storage) (maze.Level, error) {
+	if path == "-" {
+		return maze.ReadLevel(bufio.NewScanner(os.Stdin), storage)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return maze.Level{}, err
+	}
+	defer f.Close()
+
+	level, err := maze.ReadLevel(bufio.NewScanner(f), storage)
+	if err != nil {
+		return level, fmt.Errorf("%s: %v", path, err)
+	}
+	return level, nil
+}
+
+// levelOrGenerate loads the --input level, or generates a new maze if there is none.
+// The level gets the movement rules given with --diagonal and --corners.
+func (opts *options) levelOrGenerate() (maze.Level, error) {
+	var level maze.Level
+	var err error
+	if opts.input != "" {
+		opts.seedRandom()
+		var storage maze.Storage
+		if storage, err = opts.tileStorage(); err != nil {
+			return level, err
+		}
+		level, err = loadLevel(opts.input, storage)
+	} else {
+		level, err = opts.generate()
+	}
+	if err != nil {
+		return level, err
+	}
+	if opts.nearest {
+		for _, actor := range level.Actors {
+			actor.AnyExit = true
+		}
+	}
+
+	level.Movement.Diagonal = opts.diagonal
+	if opts.corners != "" {
+		if level.Movement.Corners, err = maze.ParseCornerRule(opts.corners); err != nil {
+			return level, err
+		}
+	}
+	return level, nil
+}
+
+// createOutput opens the --output file for writing, stdout if not set. The
+// returned function must be called to close the file.
+func (opts *options) createOutput() (io.Writer, func() error, error) {
+	if opts.output == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(opts.output)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: maze <command> [options]\n\nCommands:\n")
+	var cmds []string
+	for name := range commands {
+		cmds = append(cmds, name)
+	}
+	sort.Strings(cmds)
+	for _, name := range cmds {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'maze <command> -h' for the options of a command.\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "maze: unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "maze %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
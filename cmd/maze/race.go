@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mpihlak/maze"
+)
+
+// runRace races two walkers to the opposite exits of a maze. When the maze
+// is loaded from a level file, the actors on the map race to the destinations
+// given in the file.
+func runRace(args []string) error {
+	var opts options
+	fs := newFlagSet("race", "[options] [LEVEL]\n\nLEVEL is a level file to race on, - for stdin.", &opts,
+		concat(levelOptions, walkerOptions, terminalOptions, controllerOptions)...)
+	opts.parse(fs, args)
+	if fs.NArg() > 0 {
+		opts.input = fs.Arg(0)
+	}
+
+	render, err := opts.newTerminal()
+	if err != nil {
+		return err
+	}
+	defer render.Done()
+	opts.fitTerminal(render)
+
+	level, err := opts.levelOrGenerate()
+	if err != nil {
+		return err
+	}
+
+	if len(level.Actors) > 0 {
+		if err := opts.assignWalkers(&level); err != nil {
+			return err
+		}
+	} else {
+		if len(level.Exits) < 2 {
+			return fmt.Errorf("the level needs 2 exits to race, it has %d", len(level.Exits))
+		}
+		for i, c := range []rune{'@', '&'} {
+			actor, err := opts.newActor(c, level.Exits[i], level.Exits[1-i])
+			if err != nil {
+				return err
+			}
+			level.AddActor(actor)
+		}
+	}
+
+	runController(&level, render, &opts)
+	return nil
+}
+
+// runPlay lets the user walk from the first exit to the second with the arrow
+// keys, optionally racing against a walker.
+func runPlay(args []string) error {
+	var opts options
+	fs := newFlagSet("play", "[options]", &opts, concat(levelOptions, walkerOptions, terminalOptions, controllerOptions)...)
+	fs.Lookup("walker").Usage = "opponent walker, none if empty: " + walkerNames()
+	fs.Lookup("walker").DefValue = ""
+	opts.walker = ""
+	opts.parse(fs, args)
+
+	render, err := opts.newTerminal()
+	if err != nil {
+		return err
+	}
+	defer render.Done()
+	opts.fitTerminal(render)
+
+	level, err := opts.levelOrGenerate()
+	if err != nil {
+		return err
+	}
+	if len(level.Exits) < 2 {
+		return fmt.Errorf("the level needs 2 exits to play, it has %d", len(level.Exits))
+	}
+
+	player := maze.NewActor('@', level.Exits[0], level.Exits[1], &maze.KeyboardWalker{})
+	player.AnyExit = opts.nearest
+	level.AddActor(player)
+	if opts.walker != "" {
+		opponent, err := opts.newActor('&', level.Exits[0], level.Exits[1])
+		if err != nil {
+			return err
+		}
+		level.AddActor(opponent)
+	}
+
+	runController(&level, render, &opts)
+	return nil
+}
+
+// fitTerminal sizes the maze to the terminal unless the dimensions were given
+// on the command line. One line is left for the banner, and room on the side for
+// the status panel if it's shown and the terminal is wide enough.
+func (opts *options) fitTerminal(render *maze.DiffRenderer) {
+	width, height := render.Size()
+	if opts.status && width > 2*(maze.StatusWidth+1) {
+		width -= maze.StatusWidth + 1
+	}
+	if !opts.explicit["width"] {
+		// The floors are drawn side by side, one column apart
+		opts.width = width
+		if opts.floors > 1 {
+			opts.width = (width+1)/opts.floors - 1
+		}
+		opts.width *= render.WallStyle().ColsPerChar()
+	}
+	if !opts.explicit["height"] {
+		opts.height = (height - 1) * render.WallStyle().RowsPerLine()
+	}
+}
+
+// runController runs the actors on the level until they're done, with the search
+// and the status panel shown as the options say
+func runController(level *maze.Level, render maze.Renderer, opts *options) {
+	controller := maze.NewController(level, render)
+	if opts.search {
+		controller.AnimateSearch()
+	}
+	controller.ShowStatus(opts.status)
+	if opts.input == "" {
+		controller.SetSeed(opts.seed)
+	}
+	controller.Start()
+	for controller.RunLoop() {
+		// RunLoop takes care of rendering and keyboard events.
+	}
+	controller.Done()
+}
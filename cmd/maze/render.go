@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mpihlak/maze"
+)
+
+// runRender renders a level file as text
+func runRender(args []string) error {
+	var opts options
+	fs := newFlagSet("render", "-input FILE [options]", &opts, "input", "walls", "output")
+	opts.parse(fs, args)
+
+	if opts.input == "" {
+		fs.Usage()
+		return fmt.Errorf("no -input given")
+	}
+	level, err := loadLevel(opts.input, maze.DenseStorage)
+	if err != nil {
+		return err
+	}
+
+	out, closeOutput, err := opts.createOutput()
+	if err != nil {
+		return err
+	}
+	render := maze.NewStreamRendererTo(out)
+	if err := opts.styleWalls(render); err != nil {
+		closeOutput()
+		return err
+	}
+	if err := maze.Render(level, opts.input, render); err != nil {
+		closeOutput()
+		return err
+	}
+	return closeOutput()
+}
+
+// formats maps the --format names to level writers
+var formats = map[string]func(w io.Writer, level maze.Level, banner string, opts *options) error{
+	"ascii": func(w io.Writer, level maze.Level, banner string, opts *options) error {
+		return maze.WriteLevel(w, level)
+	},
+	"text": func(w io.Writer, level maze.Level, banner string, opts *options) error {
+		render := maze.NewStreamRendererTo(w)
+		if err := opts.styleWalls(render); err != nil {
+			return err
+		}
+		render.SetHeatmap(opts.heatmap)
+		return maze.Render(level, banner, render)
+	},
+	"svg": func(w io.Writer, level maze.Level, banner string, opts *options) error {
+		if opts.heatmap {
+			return maze.WriteHeatmapSVG(w, level)
+		}
+		return maze.WriteSVG(w, level)
+	},
+	"heatmap-png": func(w io.Writer, level maze.Level, banner string, opts *options) error {
+		return maze.WriteHeatmapPNG(w, level)
+	},
+}
+
+// formatNames lists the --format choices
+func formatNames() string {
+	var keys []string
+	for k := range formats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
+
+// runConvert reads a level file and writes it in another format
+func runConvert(args []string) error {
+	var opts options
+	fs := newFlagSet("convert", "-input FILE -format FORMAT [options]", &opts, "input", "output", "format")
+	opts.parse(fs, args)
+
+	if opts.input == "" {
+		fs.Usage()
+		return fmt.Errorf("no -input given")
+	}
+	level, err := loadLevel(opts.input, maze.DenseStorage)
+	if err != nil {
+		return err
+	}
+
+	out, closeOutput, err := opts.createOutput()
+	if err != nil {
+		return err
+	}
+	if err := opts.writeLevel(out, level, "", "ascii"); err != nil {
+		closeOutput()
+		return err
+	}
+	return closeOutput()
+}
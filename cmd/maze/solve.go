@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mpihlak/maze"
+)
+
+// runSolve walks the actors of a level file to their destinations and prints
+// the level with the paths taken. A level with no actors gets one that walks
+// from the first exit to the second.
+func runSolve(args []string) error {
+	var opts options
+	fs := newFlagSet("solve", "[options]", &opts,
+		concat(levelOptions, walkerOptions, []string{"grid", "format", "walls", "heatmap", "output"})...)
+	opts.parse(fs, args)
+	if opts.isGridMaze() {
+		return opts.writeGridMaze(true)
+	}
+
+	level, err := opts.levelOrGenerate()
+	if err != nil {
+		return err
+	}
+	if level.Chunked() {
+		return fmt.Errorf("chunked mazes are too large to print, race or play them instead")
+	}
+
+	if len(level.Actors) == 0 {
+		if len(level.Exits) < 2 {
+			return fmt.Errorf("the level needs 2 exits to solve, it has %d", len(level.Exits))
+		}
+		actor, err := opts.newActor('&', level.Exits[0], level.Exits[1])
+		if err != nil {
+			return err
+		}
+		level.AddActor(actor)
+	} else if err := opts.assignWalkers(&level); err != nil {
+		return err
+	}
+	for _, actor := range level.Actors {
+		actor.PathNav.Initialize(&level, actor)
+	}
+
+	// Walk until everyone is out, or until the walkers have obviously got lost.
+	width, height := level.Size()
+	steps := 0
+	for maxSteps := 4 * width * height; !allFinished(level) && steps < maxSteps; steps++ {
+		for _, actor := range level.Actors {
+			if !actor.HasFinished() {
+				actor.Step()
+			}
+		}
+	}
+
+	out, closeOutput, err := opts.createOutput()
+	if err != nil {
+		return err
+	}
+	banner := fmt.Sprintf("Seed=%v Steps=%v Finished=%v.", opts.seed, steps, allFinished(level))
+	if err := opts.writeLevel(out, level, banner, "text"); err != nil {
+		closeOutput()
+		return err
+	}
+	return closeOutput()
+}
+
+func allFinished(level maze.Level) bool {
+	for _, actor := range level.Actors {
+		if !actor.HasFinished() {
+			return false
+		}
+	}
+	return true
+}
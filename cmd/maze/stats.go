@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mpihlak/maze"
+)
+
+// runStats prints the metrics of a maze, solved from the first exit to the second
+func runStats(args []string) error {
+	var opts options
+	fs := newFlagSet("stats", "[options]", &opts, concat(levelOptions, []string{"output"})...)
+	opts.parse(fs, args)
+
+	level, err := opts.levelOrGenerate()
+	if err != nil {
+		return err
+	}
+	if level.Chunked() {
+		return fmt.Errorf("chunked mazes are too large to analyze")
+	}
+	if len(level.Exits) < 2 {
+		return fmt.Errorf("the level needs 2 exits to analyze, it has %d", len(level.Exits))
+	}
+	m := maze.Analyze(level, level.Exits[0], level.Exits[1])
+
+	out, closeOutput, err := opts.createOutput()
+	if err != nil {
+		return err
+	}
+	width, height := level.Size()
+	if opts.input == "" {
+		fmt.Fprintf(out, "Seed:             %d\n", opts.seed)
+		fmt.Fprintf(out, "Attempts:         %d\n", opts.attempts)
+	}
+	if floors := level.Floors(); floors > 1 {
+		fmt.Fprintf(out, "Size:             %dx%d, %d floors\n", width, height, floors)
+	} else {
+		fmt.Fprintf(out, "Size:             %dx%d\n", width, height)
+	}
+	fmt.Fprintf(out, "Walkable tiles:   %d\n", m.Cells)
+	fmt.Fprintf(out, "Dead ends:        %d (%.1f%% of tiles)\n", m.DeadEnds, 100*m.DeadEndDensity())
+	fmt.Fprintf(out, "Junctions:        %d\n", m.Junctions)
+	fmt.Fprintf(out, "Solution length:  %d\n", m.SolutionLength)
+	fmt.Fprintf(out, "Tortuosity:       %.2f\n", m.Tortuosity)
+	fmt.Fprintf(out, "River factor:     %.2f\n", m.RiverFactor)
+	fmt.Fprintf(out, "Decision points:  %d\n", m.Decisions)
+	fmt.Fprintf(out, "Corridors:        mean length %.2f, longest %d\n", m.MeanCorridorLength(), m.LongestCorridor())
+
+	var lengths []int
+	for length := range m.Corridors {
+		lengths = append(lengths, length)
+	}
+	sort.Ints(lengths)
+	for _, length := range lengths {
+		fmt.Fprintf(out, "  %4d: %d\n", length, m.Corridors[length])
+	}
+	return closeOutput()
+}
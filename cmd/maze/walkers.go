@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mpihlak/maze"
+)
+
+// runWalkers lists the walkers with their settings
+func runWalkers(args []string) error {
+	var opts options
+	fs := newFlagSet("walkers", "\n\nThe settings of a walker follow its name after colons, eg. --walker wallfollower:hand=left.", &opts)
+	opts.parse(fs, args)
+
+	for _, info := range maze.Walkers() {
+		fmt.Printf("%-14s %s\n", info.Name, info.Description)
+		for _, p := range info.Params {
+			fmt.Printf("  %-12s %s (default %s)\n", p.Name, p.Description, p.Default)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Package maze, racing walkers side by side on copies of the same maze
+package maze
+
+import (
+	"fmt"
+	"time"
+)
+
+// Comparison races walkers against each other on copies of the same level, each in
+// a pane of it's own so they don't get in each other's way. The walkers take their
+// steps in lockstep and each pane shows the steps taken and the place the walker
+// finished in.
+type Comparison struct {
+	panes    []*pane
+	render   Renderer
+	frame    int
+	finished int // Number of walkers finished so far
+	maxSteps int // Rounds of steps after which the walkers still going are considered lost
+}
+
+// pane is one walker of a Comparison
+type pane struct {
+	name  string
+	level Level
+	actor *Actor
+	place int // Finishing place, 0 while still going
+	lost  bool
+}
+
+// NewComparison creates a comparison of the walkers on the level. The walkers set off
+// from the first exit to the second one, or like the first actor of the level if it
+// has actors. The names are shown on the panes, the name of the walker (see
+// WalkerSpec) is used for the missing ones.
+//
+// Fails if there are no walkers, if the level has neither actors nor two exits, or
+// if a walker is steered by the keyboard: the walkers are raced against each other,
+// not against the user.
+func NewComparison(level Level, walkers []Walker, names []string, render Renderer) (*Comparison, error) {
+	if len(walkers) == 0 {
+		return nil, fmt.Errorf("no walkers to compare")
+	}
+	if len(level.Actors) == 0 && len(level.Exits) < 2 {
+		return nil, fmt.Errorf("the level needs 2 exits or an actor to compare walkers, it has neither")
+	}
+	c := &Comparison{render: render, maxSteps: 4 * level.floors * level.width * level.height}
+	for i, w := range walkers {
+		if _, ok := w.(KeyHandler); ok {
+			return nil, fmt.Errorf("the %s walker is steered by the keyboard, it can't be compared", WalkerName(w))
+		}
+		var actor *Actor
+		if len(level.Actors) > 0 {
+			a := level.Actors[0]
+			actor = NewActor(a.Character, a.CurrPos, a.EndPos, w)
+			actor.Color, actor.AnyExit = a.Color, a.AnyExit
+		} else {
+			actor = NewActor('@', level.Exits[0], level.Exits[1], w)
+		}
+
+		// The tiles are shared, the walkers don't change them
+		clone := level
+		clone.Actors = []*Actor{actor}
+
+		name := WalkerSpec(w)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		c.panes = append(c.panes, &pane{name: name, level: clone, actor: actor})
+	}
+	return c, nil
+}
+
+// Start gets the walkers ready to go
+func (c *Comparison) Start() {
+	for _, p := range c.panes {
+		p.actor.PathNav.Initialize(&p.level, p.actor)
+	}
+}
+
+// RunLoop is called in a loop to move each walker a step, render the panes and
+// collect keyboard events. Returns false when everyone is done.
+func (c *Comparison) RunLoop() bool {
+	c.draw()
+
+	isDone := true
+	finished := c.finished // The ones finishing on the same step share the place
+	for _, p := range c.panes {
+		if p.place > 0 || p.lost {
+			continue
+		}
+		p.actor.Step()
+		if p.actor.HasFinished() {
+			c.finished++
+			p.place = finished + 1
+		} else if c.frame+1 >= c.maxSteps {
+			p.lost = true
+		} else {
+			isDone = false
+		}
+	}
+
+	for polling := true; polling; {
+		select {
+		case k, ok := <-c.render.GetKeyboardEvent():
+			if !ok {
+				// The input has ended
+				isDone, polling = true, false
+			} else if k == KBEventCancel {
+				isDone = true
+			}
+		default:
+			polling = false
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	c.frame++
+	return !isDone
+}
+
+// Done shows the final standings and waits for a key, or for the input to end
+func (c *Comparison) Done() {
+	c.draw()
+	<-c.render.GetKeyboardEvent()
+}
+
+// Results returns the panes as text, one line per walker: the name, the steps taken
+// and the place
+func (c *Comparison) Results() []string {
+	var lines []string
+	for _, p := range c.panes {
+		lines = append(lines, p.banner())
+	}
+	return lines
+}
+
+// banner is the line on top of the pane
+func (p *pane) banner() string {
+	switch {
+	case p.place > 0:
+		return fmt.Sprintf("%s: %d steps, #%d", p.name, p.actor.Steps, p.place)
+	case p.lost:
+		return fmt.Sprintf("%s: %d steps, lost", p.name, p.actor.Steps)
+	}
+	return fmt.Sprintf("%s: %d steps", p.name, p.actor.Steps)
+}
+
+// draw renders the panes side by side, in as many rows as needed
+func (c *Comparison) draw() {
+	width, height := c.render.Size()
+	level := c.panes[0].level
+
+	// As many panes across as fit, but at least one
+	paneWidth := levelColumns(c.render, level.width)
+	across := (width + 1) / (paneWidth + 1)
+	if across < 1 {
+		across = 1
+	}
+	if across > len(c.panes) {
+		across = len(c.panes)
+	}
+	if paneWidth > (width+1)/across-1 {
+		paneWidth = (width+1)/across - 1
+	}
+	down := (len(c.panes) + across - 1) / across
+	paneHeight := height / down
+
+	frames := make([]Frame, len(c.panes))
+	for i, p := range c.panes {
+		b := NewBufferRenderer(paneWidth, paneHeight)
+		b.SetWallStyle(wallStyleOf(c.render))
+		b.SetHeatmap(heatmapOf(c.render))
+		v := p.level.wholeView()
+		if levelColumns(b, level.width) > paneWidth || levelLines(b, level.height)+1 > paneHeight {
+			v = p.level.viewAround(p.actor.CurrPos, b)
+		}
+		renderFloors(p.level, []int{p.actor.CurrPos.floor}, v, p.banner(), b, nil, nil)
+		frames[i] = b.LastFrame()
+	}
+
+	c.render.Reset()
+	for first := 0; first < len(frames); first += across {
+		for line := 0; line < paneHeight; line++ {
+			for i := first; i < first+across && i < len(frames); i++ {
+				if i > first {
+					c.render.PutChar(' ')
+				}
+				drawLine(frames[i], line, paneWidth, c.render)
+			}
+			c.render.NextLine()
+		}
+	}
+	c.render.Flush()
+}
+
+// drawLine draws a line of the frame on the renderer, padded to the width
+func drawLine(f Frame, line, width int, r Renderer) {
+	var cells []cell
+	if line < len(f) {
+		cells = f[line]
+	}
+	for col := 0; col < width; col++ {
+		k := blankCell
+		if col < len(cells) {
+			k = cells[col]
+		}
+		r.SetColor(k.fg, k.bg)
+		r.PutChar(k.c)
+	}
+	r.SetColor(ColorDefault, ColorDefault)
+}
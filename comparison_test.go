@@ -0,0 +1,56 @@
+package maze
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// corridor is a level with a single way from the actor to the exit, 3 steps long
+const corridor = `###
+#@#
+# #
+# #
+#=#
+`
+
+func TestComparisonCountsMoves(t *testing.T) {
+	var walkers []Walker
+	for _, name := range []string{"shortestpath", "shortestline", "wallfollower"} {
+		w, err := NewWalker(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walkers = append(walkers, w)
+	}
+	c, err := NewComparison(readTestLevel(t, corridor), walkers, nil, NewBufferRenderer(80, 24))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Start()
+	for rounds := 0; c.RunLoop(); rounds++ {
+		if rounds > 10 {
+			t.Fatal("the walkers haven't finished in 10 rounds")
+		}
+	}
+	var want []string
+	for _, w := range walkers {
+		want = append(want, fmt.Sprintf("%s: 3 steps, #", WalkerSpec(w)))
+	}
+	got := c.Results()
+	for i := range got {
+		// The places depend on the walkers, the steps don't
+		if len(got[i]) > len(want[i]) {
+			got[i] = got[i][:len(want[i])]
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestComparisonNeedsWalkers(t *testing.T) {
+	if _, err := NewComparison(readTestLevel(t, corridor), nil, nil, NewBufferRenderer(80, 24)); err == nil {
+		t.Error("compared no walkers")
+	}
+}
@@ -9,9 +9,19 @@ import (
 // Controller manages the actors on the level, responds to keyboard events and
 // renders the maze.
 type Controller struct {
-	frame  int
-	level  *Level
-	render Renderer
+	frame    int
+	level    *Level
+	render   Renderer
+	selected int  // Index of the selected actor
+	oneFloor bool // Show only the floor of the selected actor
+	status   bool // Show the status panel, see statusLines
+	seed     int64
+	hasSeed  bool
+
+	animateSearch bool               // Show the walkers searching for their paths before they set off
+	search        []SearchEvent      // Search events still to be shown
+	frontier      map[Position]glyph // The search so far, drawn over the level
+	searchStep    int                // Search events shown per frame
 }
 
 func NewController(level *Level, render Renderer) *Controller {
@@ -19,32 +29,114 @@ func NewController(level *Level, render Renderer) *Controller {
 	return &c
 }
 
+// AnimateSearch has the Controller show how the walkers that can tell about their
+// search for the path (see SearchTracer) find it, before the actors set off. Must be
+// called before Start.
+func (c *Controller) AnimateSearch() {
+	c.animateSearch = true
+}
+
+// ShowStatus turns the status panel with the state of each actor on or off. The 's'
+// key toggles it too.
+func (c *Controller) ShowStatus(show bool) {
+	c.status = show
+}
+
+// SetSeed tells the seed the level was generated with, for the status panel
+func (c *Controller) SetSeed(seed int64) {
+	c.seed, c.hasSeed = seed, true
+}
+
 func (c *Controller) Start() {
 	for _, actor := range c.level.Actors {
-		actor.PathNav.Initialize(c.level, actor)
+		if t, ok := actor.PathNav.(SearchTracer); ok && c.animateSearch {
+			t.TraceSearch(func(e SearchEvent) { c.search = append(c.search, e) })
+			actor.PathNav.Initialize(c.level, actor)
+			t.TraceSearch(nil)
+		} else {
+			actor.PathNav.Initialize(c.level, actor)
+		}
+	}
+	// Show the search in about 10 seconds
+	c.searchStep = 1 + len(c.search)/100
+}
+
+// showSearch adds the next search events to the frontier. A search for the path of
+// the next actor starts with a clean slate.
+func (c *Controller) showSearch() {
+	for i := 0; i < c.searchStep && len(c.search) > 0; i++ {
+		e := c.search[0]
+		c.search = c.search[1:]
+		if c.frontier == nil || (e.Kind == NodeEnqueued && e.Cost == 0) {
+			c.frontier = make(map[Position]glyph)
+		}
+		switch e.Kind {
+		case NodeEnqueued:
+			c.frontier[e.Pos] = glyph{c: '+', color: ColorYellow}
+		case NodeExpanded:
+			c.frontier[e.Pos] = glyph{c: '.', color: ColorBlue}
+		case PathFound:
+			for _, pos := range e.Path {
+				c.frontier[pos] = glyph{c: '*', color: ColorGreen}
+			}
+			// Let the path be seen for a moment
+			i = c.searchStep
+		}
 	}
 }
 
 // RunLoop is called in a loop to update the state of the moving objects,
 // render the maze and collect keyboard events.
 func (c *Controller) RunLoop() bool {
-	Render(*c.level, fmt.Sprintf("render #%d", c.frame), c.render)
-
 	isDone := false
-
-	for _, actor := range c.level.Actors {
-		actor.PathNav.NextPosition()
-		if actor.HasFinished() {
-			isDone = true
+	if len(c.search) > 0 {
+		c.showSearch()
+		c.draw(fmt.Sprintf("search #%d", c.frame))
+	} else {
+		c.frontier = nil
+		c.draw(fmt.Sprintf("render #%d", c.frame))
+		for _, actor := range c.level.Actors {
+			actor.Step()
+			if actor.HasFinished() {
+				isDone = true
+			}
 		}
 	}
 
-	select {
-	case k := <-c.render.GetKeyboardEvent():
-		if k == KBEventCancel {
-			isDone = true
+	// Drain the pending keyboard events, walkers that steer by keyboard get
+	// to see them all.
+	for polling := true; polling; {
+		select {
+		case k, ok := <-c.render.GetKeyboardEvent():
+			if !ok {
+				// The input has ended, nobody's there to stop it otherwise
+				isDone, polling = true, false
+				break
+			}
+			switch k {
+			case KBEventCancel:
+				isDone = true
+			case KBEventNextActor:
+				if len(c.level.Actors) > 0 {
+					c.selected = (c.selected + 1) % len(c.level.Actors)
+				}
+			case KBEventFloors:
+				c.oneFloor = !c.oneFloor
+			case KBEventStatus:
+				c.status = !c.status
+			case KBEventHeatmap:
+				if h, ok := c.render.(interface{ SetHeatmap(bool) }); ok {
+					h.SetHeatmap(!heatmapOf(c.render))
+				}
+			}
+			for _, actor := range c.level.Actors {
+				if h, ok := actor.PathNav.(KeyHandler); ok {
+					h.HandleKey(k)
+				}
+			}
+		default:
+			polling = false
 		}
-	default:
 	}
 
 	time.Sleep(100 * time.Millisecond)
@@ -53,7 +145,55 @@ func (c *Controller) RunLoop() bool {
 	return !isDone
 }
 
+// Done shows the final frame and waits for a key, or for the input to end
 func (c *Controller) Done() {
-	Render(*c.level, fmt.Sprintf("Woohoo! Done after %d iterations. Press any key to exit...", c.frame), c.render)
+	c.draw(fmt.Sprintf("Woohoo! Done after %d iterations. Press any key to exit...", c.frame))
 	<-c.render.GetKeyboardEvent()
 }
+
+// draw renders either all of the floors or just the one the selected actor is on.
+// If the level doesn't fit the renderer, the part around the selected actor is drawn.
+func (c *Controller) draw(banner string) {
+	level := *c.level
+	if stats, ok := frameStatsOf(c.render); ok {
+		banner = fmt.Sprintf("%s [%s]", banner, stats)
+	}
+	var panel []string
+	if c.status {
+		panel = statusLines(level, c.seed, c.hasSeed)
+	}
+	if c.selected >= len(level.Actors) {
+		renderFloors(level, level.allFloors(), level.wholeView(), banner, c.render, c.frontier, panel)
+		return
+	}
+	actor := level.Actors[c.selected]
+	floor := actor.CurrPos.Floor()
+	if !c.fits() {
+		banner = fmt.Sprintf("%s [%c at %d,%d]", banner, actor.Character, actor.CurrPos.row, actor.CurrPos.col)
+		// Leave room for the status panel on the side, or below if that leaves little
+		// for the level
+		width, height := c.render.Size()
+		if panel != nil && width > 2*(StatusWidth+1) {
+			width -= StatusWidth + 1
+		} else if panel != nil && height > 2*len(panel) {
+			height -= len(panel)
+		}
+		v := level.viewAroundIn(actor.CurrPos, width, height, wallStyleOf(c.render))
+		renderFloors(level, []int{floor}, v, banner, c.render, c.frontier, panel)
+	} else if c.oneFloor {
+		banner = fmt.Sprintf("%s [%c on floor %d]", banner, actor.Character, floor)
+		renderFloors(level, []int{floor}, level.wholeView(), banner, c.render, c.frontier, panel)
+	} else {
+		renderFloors(level, level.allFloors(), level.wholeView(), banner, c.render, c.frontier, panel)
+	}
+}
+
+// fits tells if the floors of the level shown fit the renderer
+func (c *Controller) fits() bool {
+	width, height := c.render.Size()
+	floors := c.level.floors
+	if c.oneFloor {
+		floors = 1
+	}
+	return floors*(levelColumns(c.render, c.level.width)+1)-1 <= width && levelLines(c.render, c.level.height)+1 <= height
+}
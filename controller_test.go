@@ -0,0 +1,76 @@
+package maze
+
+import (
+	"fmt"
+	"testing"
+)
+
+// startController starts a controller on the level, drawing on a BufferRenderer of
+// the given size
+func startController(t *testing.T, text string, width, height int) (*Controller, *BufferRenderer) {
+	t.Helper()
+	level := readTestLevel(t, text)
+	r := NewBufferRenderer(width, height)
+	c := NewController(&level, r)
+	c.Start()
+	return c, r
+}
+
+// runFrames runs the controller for the number of frames, failing if it stops
+func runFrames(t *testing.T, c *Controller, frames int) {
+	t.Helper()
+	for i := 0; i < frames; i++ {
+		if !c.RunLoop() {
+			t.Fatalf("controller stopped after %d frames", i+1)
+		}
+	}
+}
+
+func TestControllerRunLoop(t *testing.T) {
+	c, r := startController(t, crossing, 80, 24)
+	runFrames(t, c, 4)
+	if len(r.Frames()) != 4 {
+		t.Fatalf("got %d frames, want 4", len(r.Frames()))
+	}
+	for i, f := range r.Frames() {
+		checkGolden(t, f, fmt.Sprintf("controller-frame%d", i))
+	}
+}
+
+func TestControllerStatusKey(t *testing.T) {
+	c, r := startController(t, crossing, 80, 24)
+	r.SendKeys(KBEventStatus)
+	// The key is handled after the frame is drawn
+	runFrames(t, c, 2)
+	checkGolden(t, r.LastFrame(), "controller-status")
+
+	r.SendKeys(KBEventStatus)
+	runFrames(t, c, 2)
+	checkGolden(t, r.LastFrame(), "controller-status-off")
+}
+
+func TestControllerFloorsKey(t *testing.T) {
+	c, r := startController(t, twoFloors, 80, 24)
+	r.SendKeys(KBEventFloors)
+	runFrames(t, c, 2)
+	checkGolden(t, r.LastFrame(), "controller-one-floor")
+}
+
+func TestControllerFollowsSelectedActor(t *testing.T) {
+	// Too small for the level, the part around the selected actor is drawn
+	c, r := startController(t, crossing, 30, 6)
+	runFrames(t, c, 1)
+	checkGolden(t, r.LastFrame(), "controller-follow-first")
+
+	r.SendKeys(KBEventNextActor)
+	runFrames(t, c, 2)
+	checkGolden(t, r.LastFrame(), "controller-follow-next")
+}
+
+func TestControllerCancelKey(t *testing.T) {
+	c, r := startController(t, crossing, 80, 24)
+	r.SendKeys(KBEventCancel)
+	if c.RunLoop() {
+		t.Error("controller carried on after cancel")
+	}
+}
@@ -0,0 +1,107 @@
+// Package maze, generating mazes of a given difficulty
+package maze
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Difficulty is a target for the metrics of a generated maze. Zero fields are not checked.
+type Difficulty struct {
+	MinSolutionLength int
+	MaxSolutionLength int
+	MinDeadEndDensity float64
+	MaxDeadEndDensity float64
+	MinDecisions      int
+	MaxDecisions      int
+}
+
+// DifficultyPresets lists the names accepted by DifficultyPreset, from easy to hard
+var DifficultyPresets = []string{"easy", "medium", "hard"}
+
+// DifficultyPreset returns the named difficulty for a maze of the given size.
+// The targets scale with the distance between the top left and bottom right
+// exits that GenerateRandomMaze makes.
+func DifficultyPreset(name string, width, height int) (Difficulty, error) {
+	distance := float64(width + height - 4)
+	switch name {
+	case "easy":
+		return Difficulty{
+			MaxSolutionLength: int(1.6 * distance),
+			MaxDecisions:      int(0.12 * distance),
+		}, nil
+	case "medium":
+		return Difficulty{
+			MinSolutionLength: int(1.8 * distance),
+			MaxSolutionLength: int(2.8 * distance),
+			MinDecisions:      int(0.12 * distance),
+		}, nil
+	case "hard":
+		return Difficulty{
+			MinSolutionLength: int(2.8 * distance),
+			MinDecisions:      int(0.25 * distance),
+			MinDeadEndDensity: 0.09,
+		}, nil
+	}
+	return Difficulty{}, fmt.Errorf("unknown difficulty %q", name)
+}
+
+// Accepts tells if the metrics meet the difficulty target
+func (d Difficulty) Accepts(m Metrics) bool {
+	if m.SolutionLength < 0 {
+		return false
+	}
+	switch {
+	case d.MinSolutionLength > 0 && m.SolutionLength < d.MinSolutionLength:
+		return false
+	case d.MaxSolutionLength > 0 && m.SolutionLength > d.MaxSolutionLength:
+		return false
+	case d.MinDeadEndDensity > 0 && m.DeadEndDensity() < d.MinDeadEndDensity:
+		return false
+	case d.MaxDeadEndDensity > 0 && m.DeadEndDensity() > d.MaxDeadEndDensity:
+		return false
+	case d.MinDecisions > 0 && m.Decisions < d.MinDecisions:
+		return false
+	case d.MaxDecisions > 0 && m.Decisions > d.MaxDecisions:
+		return false
+	}
+	return true
+}
+
+// TargetedMaze is a maze generated to meet a difficulty target
+type TargetedMaze struct {
+	Level    Level
+	Metrics  Metrics // Metrics of the level, solved from the first exit to the second
+	Seed     int64   // Random seed that generates this level
+	Attempts int     // Number of mazes generated to find this one
+}
+
+// GenerateWithDifficulty calls generate with successive random seeds starting from
+// seed until it comes up with a maze that meets the target. A seed that generate
+// fails with is skipped like one that misses the target. Gives up with an error
+// after maxAttempts tries, the last error of generate if it never succeeded.
+func GenerateWithDifficulty(generate func() (Level, error), target Difficulty, seed int64, maxAttempts int) (TargetedMaze, error) {
+	var lastErr error
+	generated := false
+	for attempt := 1; attempt <= maxAttempts; attempt, seed = attempt+1, seed+1 {
+		rand.Seed(seed)
+		level, err := generate()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		generated = true
+		if len(level.Exits) < 2 {
+			return TargetedMaze{}, fmt.Errorf("the generated level has %d exits, need 2 to measure the difficulty", len(level.Exits))
+		}
+
+		m := Analyze(level, level.Exits[0], level.Exits[1])
+		if target.Accepts(m) {
+			return TargetedMaze{Level: level, Metrics: m, Seed: seed, Attempts: attempt}, nil
+		}
+	}
+	if !generated && lastErr != nil {
+		return TargetedMaze{}, lastErr
+	}
+	return TargetedMaze{}, fmt.Errorf("no maze met the difficulty target in %d attempts", maxAttempts)
+}
@@ -0,0 +1,29 @@
+package maze
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGenerateWithDifficultySkipsFailedSeeds(t *testing.T) {
+	seed := int64(0)
+	generate := func() (Level, error) {
+		seed++
+		if seed%2 == 1 {
+			return Level{}, errors.New("no room")
+		}
+		return GenerateRandomMaze(21, 11)
+	}
+	m, err := GenerateWithDifficulty(generate, Difficulty{}, 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Attempts != 2 || m.Seed != 2 {
+		t.Errorf("got attempt %d with seed %d, want attempt 2 with seed 2", m.Attempts, m.Seed)
+	}
+
+	failing := func() (Level, error) { return Level{}, errors.New("no room") }
+	if _, err := GenerateWithDifficulty(failing, Difficulty{}, 1, 5); err == nil || err.Error() != "no room" {
+		t.Errorf("got %v, want the error of generate", err)
+	}
+}
@@ -0,0 +1,140 @@
+// Package maze, drawing only what changed since the last frame
+package maze
+
+import (
+	"fmt"
+	"time"
+)
+
+// Screen is a terminal that a DiffRenderer draws on a cell at a time
+type Screen interface {
+	SetCell(col, row int, c rune, fg, bg Color)
+	Clear()
+	Flush()
+	GetKeyboardEvent() KeyboardEventChannel
+	Size() (int, int)
+	Done()
+}
+
+// cell is a character on the screen
+type cell struct {
+	c      rune
+	fg, bg Color
+}
+
+var blankCell = cell{c: ' '}
+
+// FrameStats tells how long drawing the last frame took and how many cells of the
+// screen changed
+type FrameStats struct {
+	Frames  int // Frames drawn so far
+	Time    time.Duration
+	Changed int
+}
+
+// String returns the stats for the banner
+func (s FrameStats) String() string {
+	return fmt.Sprintf("%.1fms, %d cells", float64(s.Time)/float64(time.Millisecond), s.Changed)
+}
+
+// DiffRenderer keeps the previous frame in memory and only sends the cells that
+// changed since it to the screen, instead of redrawing the whole screen every frame.
+// That makes a difference on large terminals and remote sessions.
+type DiffRenderer struct {
+	renderStyle
+	screen        Screen
+	width, height int
+	row, col      int
+	fg, bg        Color
+	prev, cur     []cell // The cells of the previous and the current frame, row by row
+	started       time.Time
+	stats         FrameStats
+}
+
+// NewDiffRenderer creates a renderer drawing on the screen
+func NewDiffRenderer(screen Screen) *DiffRenderer {
+	return &DiffRenderer{screen: screen}
+}
+
+// Stats returns the stats of the last frame flushed
+func (t *DiffRenderer) Stats() FrameStats {
+	return t.stats
+}
+
+// GetKeyboardEvent returns a channel that can be polled for keyboard events from the screen.
+func (t *DiffRenderer) GetKeyboardEvent() KeyboardEventChannel {
+	return t.screen.GetKeyboardEvent()
+}
+
+// Done is called to shut down the renderer and the screen.
+func (t *DiffRenderer) Done() {
+	t.screen.Done()
+}
+
+// Size returns the size of the screen.
+func (t *DiffRenderer) Size() (int, int) {
+	return t.screen.Size()
+}
+
+// NextLine advances the current row and resets the column to the start of the row.
+func (t *DiffRenderer) NextLine() {
+	t.row++
+	t.col = 0
+}
+
+// PutChar puts the character into the current position indicated by row and column and
+// advances the column. What's outside the screen is dropped.
+func (t *DiffRenderer) PutChar(c rune) {
+	if t.row < t.height && t.col < t.width {
+		t.cur[t.row*t.width+t.col] = cell{c, t.fg, t.bg}
+	}
+	t.col++
+}
+
+// SetColor sets the foreground and background colours for the following characters.
+func (t *DiffRenderer) SetColor(fg, bg Color) {
+	t.fg, t.bg = fg, bg
+}
+
+// Reset starts a new, blank frame from the top left corner. When the size of the
+// screen has changed, the whole screen is drawn again.
+func (t *DiffRenderer) Reset() {
+	t.started = time.Now()
+	t.row, t.col = 0, 0
+	if width, height := t.screen.Size(); width != t.width || height != t.height {
+		t.width, t.height = width, height
+		t.prev = nil
+		t.cur = make([]cell, width*height)
+		t.screen.Clear()
+	}
+	for i := range t.cur {
+		t.cur[i] = blankCell
+	}
+}
+
+// Flush sends the cells that changed since the previous frame to the screen.
+func (t *DiffRenderer) Flush() {
+	changed := 0
+	for i, c := range t.cur {
+		if t.prev == nil || t.prev[i] != c {
+			t.screen.SetCell(i%t.width, i/t.width, c.c, c.fg, c.bg)
+			changed++
+		}
+	}
+	t.screen.Flush()
+
+	if t.prev == nil {
+		t.prev = make([]cell, len(t.cur))
+	}
+	t.prev, t.cur = t.cur, t.prev
+	t.stats = FrameStats{Frames: t.stats.Frames + 1, Time: time.Since(t.started), Changed: changed}
+}
+
+// frameStatsOf returns the stats of the last frame drawn by the renderer, if it
+// keeps any and has drawn a frame
+func frameStatsOf(r Renderer) (FrameStats, bool) {
+	if s, ok := r.(interface{ Stats() FrameStats }); ok && s.Stats().Frames > 0 {
+		return s.Stats(), true
+	}
+	return FrameStats{}, false
+}
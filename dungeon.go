@@ -0,0 +1,194 @@
+// Package maze, rooms and corridors dungeons
+package maze
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Room is a rectangular room on a dungeon level. The position and size are of the
+// floor of the room, the walls are around it.
+type Room struct {
+	Top, Left     int
+	Width, Height int
+}
+
+// Contains tells if the position is on the floor of the room
+func (r Room) Contains(pos Position) bool {
+	return pos.row >= r.Top && pos.row < r.Top+r.Height && pos.col >= r.Left && pos.col < r.Left+r.Width
+}
+
+// Center returns the position in the middle of the room
+func (r Room) Center() Position {
+	return Position{row: r.Top + r.Height/2, col: r.Left + r.Width/2}
+}
+
+// overlaps tells if the rooms overlap or are too close to have a wall between them
+func (r Room) overlaps(o Room) bool {
+	return r.Left <= o.Left+o.Width && o.Left <= r.Left+r.Width &&
+		r.Top <= o.Top+o.Height && o.Top <= r.Top+r.Height
+}
+
+// DungeonConfig controls the dungeon generator
+type DungeonConfig struct {
+	Attempts int     // Number of tries to place a room, the ones that don't fit are skipped
+	MinRoom  int     // Smallest room height, rooms are twice as wide as they are high
+	MaxRoom  int     // Largest room height
+	Loops    float64 // Extra corridors making loops, per room
+}
+
+// DefaultDungeon is the configuration used by GenerateDungeon
+var DefaultDungeon = DungeonConfig{Attempts: 60, MinRoom: 2, MaxRoom: 5, Loops: 0.25}
+
+// GenerateDungeon generates a dungeon of rooms connected by corridors, with the
+// default configuration.
+func GenerateDungeon(width, height int) Level {
+	return GenerateDungeonWith(width, height, DefaultDungeon, DenseStorage)
+}
+
+// GenerateDungeonWith generates a dungeon of non-overlapping rooms. The rooms are
+// connected with corridors along a minimum spanning tree, so that every room can be
+// reached from every other, plus some extra corridors to make loops. The rooms are
+// listed in Level.Rooms. The entrance is above the topmost room and the exit below
+// the bottom one. The tiles are kept in the storage.
+func GenerateDungeonWith(width, height int, cfg DungeonConfig, storage Storage) Level {
+	level := makeEmptyLevel(width, height, 1, storage)
+	level.forEachTile(func(pos Position, t Tile) {
+		level.setTile(pos, Tile{WallTile, WallBlock})
+	})
+
+	for i := 0; i < cfg.Attempts; i++ {
+		h := cfg.MinRoom + rand.Intn(cfg.MaxRoom-cfg.MinRoom+1)
+		w := 2 * (cfg.MinRoom + rand.Intn(cfg.MaxRoom-cfg.MinRoom+1))
+		if w > width-2 || h > height-2 {
+			continue
+		}
+		room := Room{Top: 1 + rand.Intn(height-1-h), Left: 1 + rand.Intn(width-1-w), Width: w, Height: h}
+		if !level.roomFits(room) {
+			continue
+		}
+		level.Rooms = append(level.Rooms, room)
+	}
+	if len(level.Rooms) == 0 {
+		// Too small for the rooms, make the whole level one
+		level.Rooms = append(level.Rooms, Room{Top: 1, Left: 1, Width: width - 2, Height: height - 2})
+	}
+
+	for _, room := range level.Rooms {
+		for row := room.Top; row < room.Top+room.Height; row++ {
+			for col := room.Left; col < room.Left+room.Width; col++ {
+				level.setTile(Position{row: row, col: col}, Tile{EmptyTile, ' '})
+			}
+		}
+	}
+	for _, link := range dungeonLinks(level.Rooms, cfg.Loops) {
+		level.carveCorridor(level.Rooms[link[0]].Center(), level.Rooms[link[1]].Center())
+	}
+
+	top, bottom := level.Rooms[0], level.Rooms[0]
+	for _, room := range level.Rooms {
+		if room.Top < top.Top {
+			top = room
+		}
+		if room.Top+room.Height > bottom.Top+bottom.Height {
+			bottom = room
+		}
+	}
+	// Ploughing straight in from above and below always hits the rooms, so the exits
+	// can't fail
+	level.CreateHorizontalExit(Position{row: 0, col: top.Center().col})
+	level.CreateHorizontalExit(Position{row: height - 1, col: bottom.Center().col})
+	return level
+}
+
+// roomFits tells if the room doesn't overlap any of the rooms on the level
+func (level Level) roomFits(room Room) bool {
+	for _, other := range level.Rooms {
+		if room.overlaps(other) {
+			return false
+		}
+	}
+	return true
+}
+
+// dungeonLinks picks the pairs of rooms to connect with corridors: the minimum
+// spanning tree of the distances between the rooms, plus the shortest of the other
+// pairs for loops.
+func dungeonLinks(rooms []Room, loops float64) [][2]int {
+	distance := func(i, j int) int {
+		a, b := rooms[i].Center(), rooms[j].Center()
+		return abs(a.row-b.row) + abs(a.col-b.col)
+	}
+
+	// Prim's algorithm, growing the tree from the first room
+	var links [][2]int
+	inTree := make([]bool, len(rooms))
+	linked := make(map[[2]int]bool)
+	inTree[0] = true
+	for n := 1; n < len(rooms); n++ {
+		best := [2]int{-1, -1}
+		for i := range rooms {
+			for j := range rooms {
+				if !inTree[i] || inTree[j] {
+					continue
+				}
+				if best[0] < 0 || distance(i, j) < distance(best[0], best[1]) {
+					best = [2]int{i, j}
+				}
+			}
+		}
+		inTree[best[1]] = true
+		links = append(links, best)
+		linked[best] = true
+		linked[[2]int{best[1], best[0]}] = true
+	}
+
+	var others [][2]int
+	for i := range rooms {
+		for j := i + 1; j < len(rooms); j++ {
+			if !linked[[2]int{i, j}] {
+				others = append(others, [2]int{i, j})
+			}
+		}
+	}
+	sort.SliceStable(others, func(a, b int) bool {
+		return distance(others[a][0], others[a][1]) < distance(others[b][0], others[b][1])
+	})
+	extra := int(loops*float64(len(rooms)) + 0.5)
+	if extra > len(others) {
+		extra = len(others)
+	}
+	return append(links, others[:extra]...)
+}
+
+// carveCorridor digs an L-shaped corridor between the positions, turning either
+// first horizontally or first vertically
+func (level *Level) carveCorridor(from, to Position) {
+	corner := Position{row: from.row, col: to.col}
+	if rand.Intn(2) == 0 {
+		corner = Position{row: to.row, col: from.col}
+	}
+	for _, leg := range [][2]Position{{from, corner}, {corner, to}} {
+		pos, end := leg[0], leg[1]
+		dir := Direction{xd: sign(end.col - pos.col), yd: sign(end.row - pos.row)}
+		for {
+			if level.WithinFrame(pos) && level.tile(pos).tileType == WallTile {
+				level.setTile(pos, Tile{EmptyTile, ' '})
+			}
+			if pos == end {
+				break
+			}
+			pos = AddDirection(pos, dir)
+		}
+	}
+}
+
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,348 @@
+// Package maze, mazes of cells with thin walls between them.
+//
+// A Level spends a whole tile on each wall. An EdgeMaze is a rectangle of cells
+// that only keeps a bit for each side of each cell, which is how the classic maze
+// generators think of a maze. It converts to a Level and back without losing
+// anything, so the walkers and renderers work on it as well.
+package maze
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// EdgeMaze is a maze of Rows x Cols cells, each with a wall on some of it's edges.
+// The walls between cells are kept on both of them. A missing wall on the outer
+// edge of the maze is an exit.
+type EdgeMaze struct {
+	Rows, Cols int
+	walls      []Edge
+}
+
+// cellEdges are the edges of a cell in the order of ValidDirections
+var cellEdges = [4]Edge{BottomEdge, RightEdge, LeftEdge, TopEdge}
+
+// opposite returns the edge on the other side of the wall
+func opposite(e Edge) Edge {
+	switch e {
+	case TopEdge:
+		return BottomEdge
+	case BottomEdge:
+		return TopEdge
+	case LeftEdge:
+		return RightEdge
+	}
+	return LeftEdge
+}
+
+// edgeDirection returns the step across the edge
+func edgeDirection(e Edge) Direction {
+	for i, edge := range cellEdges {
+		if edge == e {
+			return ValidDirections[i]
+		}
+	}
+	panic(fmt.Sprintf("not a single edge: %v", e))
+}
+
+// NewEdgeMaze returns a maze with every cell walled in
+func NewEdgeMaze(rows, cols int) *EdgeMaze {
+	m := &EdgeMaze{Rows: rows, Cols: cols, walls: make([]Edge, rows*cols)}
+	for i := range m.walls {
+		m.walls[i] = AllEdges
+	}
+	return m
+}
+
+// Contains tells if there's a cell at row, col
+func (m *EdgeMaze) Contains(row, col int) bool {
+	return row >= 0 && col >= 0 && row < m.Rows && col < m.Cols
+}
+
+// Walls returns the edges of the cell that have a wall
+func (m *EdgeMaze) Walls(row, col int) Edge {
+	return m.walls[row*m.Cols+col]
+}
+
+// HasWall tells if there's a wall on the edge of the cell
+func (m *EdgeMaze) HasWall(row, col int, e Edge) bool {
+	return m.Walls(row, col)&e != 0
+}
+
+// neighbour returns the cell across the edge of the cell, which may be outside the maze
+func (m *EdgeMaze) neighbour(row, col int, e Edge) (int, int) {
+	dir := edgeDirection(e)
+	return row + dir.yd, col + dir.xd
+}
+
+// Carve knocks down the wall on the edge of the cell, on the outer edge of the maze
+// that makes an exit
+func (m *EdgeMaze) Carve(row, col int, e Edge) {
+	m.walls[row*m.Cols+col] &^= e
+	if r, c := m.neighbour(row, col, e); m.Contains(r, c) {
+		m.walls[r*m.Cols+c] &^= opposite(e)
+	}
+}
+
+// Build puts up a wall on the edge of the cell
+func (m *EdgeMaze) Build(row, col int, e Edge) {
+	m.walls[row*m.Cols+col] |= e
+	if r, c := m.neighbour(row, col, e); m.Contains(r, c) {
+		m.walls[r*m.Cols+c] |= opposite(e)
+	}
+}
+
+// Level converts the maze to a Level of 2*Rows+1 by 2*Cols+1 tiles. The cells are
+// on the odd rows and columns, the walls between them and the corners in between.
+// The exits are listed top to bottom, left to right. The tiles are kept in the storage.
+func (m *EdgeMaze) Level(storage Storage) Level {
+	level := makeEmptyLevel(2*m.Cols+1, 2*m.Rows+1, 1, storage)
+	level.forEachTile(func(pos Position, t Tile) {
+		if pos.row%2 == 0 || pos.col%2 == 0 {
+			level.setTile(pos, Tile{WallTile, WallBlock})
+		}
+	})
+	for row := 0; row < m.Rows; row++ {
+		for col := 0; col < m.Cols; col++ {
+			cell := Position{row: 2*row + 1, col: 2*col + 1}
+			for _, e := range cellEdges {
+				if !m.HasWall(row, col, e) {
+					level.setTile(AddDirection(cell, edgeDirection(e)), Tile{EmptyTile, ' '})
+				}
+			}
+		}
+	}
+	level.forEachTile(func(pos Position, t Tile) {
+		if !level.WithinFrame(pos) && t.tileType == EmptyTile {
+			level.Exits = append(level.Exits, pos)
+		}
+	})
+	return level
+}
+
+// EdgeMazeFromLevel converts a level back to an EdgeMaze. Only the maze itself is
+// converted, not the actors or anything else in the legend. The level has to be
+// shaped like the ones EdgeMaze.Level makes: a single floor with the cells on the odd
+// rows and columns and walls on all the corners between them, without stairs or
+// void, and with exactly the openings in the frame as exits.
+func EdgeMazeFromLevel(level Level) (*EdgeMaze, error) {
+	if level.floors != 1 || level.width%2 == 0 || level.height%2 == 0 || level.width < 3 || level.height < 3 {
+		return nil, fmt.Errorf("level must have one floor and an odd width and height, it's %dx%d with %d floors",
+			level.width, level.height, level.floors)
+	}
+	m := NewEdgeMaze(level.height/2, level.width/2)
+	var err error
+	level.forEachTile(func(pos Position, t Tile) {
+		if err != nil {
+			return
+		}
+		cellRow, cellCol := pos.row%2 == 1, pos.col%2 == 1
+		switch {
+		case t.tileType != EmptyTile && t.tileType != WallTile:
+			err = fmt.Errorf("%d,%d: only walls and empty tiles fit in cells and walls", pos.row, pos.col)
+		case cellRow && cellCol && t.tileType != EmptyTile:
+			err = fmt.Errorf("%d,%d: a cell must be empty", pos.row, pos.col)
+		case !cellRow && !cellCol && t.tileType != WallTile:
+			err = fmt.Errorf("%d,%d: a corner between cells must be a wall", pos.row, pos.col)
+		case t.tileType == EmptyTile && cellRow != cellCol:
+			// An opening in the wall between two cells, or an exit
+			switch {
+			case cellRow && pos.col == 0:
+				m.Carve(pos.row/2, 0, LeftEdge)
+			case cellRow:
+				m.Carve(pos.row/2, pos.col/2-1, RightEdge)
+			case pos.row == 0:
+				m.Carve(0, pos.col/2, TopEdge)
+			default:
+				m.Carve(pos.row/2-1, pos.col/2, BottomEdge)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The openings in the frame and the exits must be the same, in any order
+	openings := make(map[Position]bool)
+	level.forEachTile(func(pos Position, t Tile) {
+		if !level.WithinFrame(pos) && t.tileType == EmptyTile {
+			openings[pos] = true
+		}
+	})
+	for _, pos := range level.Exits {
+		if !openings[pos] {
+			return nil, fmt.Errorf("%d,%d: the exit is not an opening in the frame", pos.row, pos.col)
+		}
+		delete(openings, pos)
+	}
+	for pos := range openings {
+		return nil, fmt.Errorf("%d,%d: the opening in the frame is not an exit", pos.row, pos.col)
+	}
+	return m, nil
+}
+
+// EdgeGenerators maps the names of the classic maze generators to the generators.
+// They all make perfect mazes: every cell can be reached from every other cell by
+// exactly one path.
+var EdgeGenerators = map[string]func(rows, cols int) *EdgeMaze{
+	"binarytree": GenerateBinaryTree,
+	"sidewinder": GenerateSidewinder,
+	"prim":       GeneratePrim,
+	"kruskal":    GenerateKruskal,
+}
+
+// GenerateEdgeLevel generates a level of about width x height tiles with one of the
+// EdgeGenerators. The size is rounded down to odd numbers so that the cells fit, and
+// like in GenerateRandomMaze there's an exit in the top left and the bottom right.
+// The tiles are kept in the storage.
+func GenerateEdgeLevel(generator string, width, height int, storage Storage) (Level, error) {
+	gen, ok := EdgeGenerators[generator]
+	if !ok {
+		var names []string
+		for name := range EdgeGenerators {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Level{}, fmt.Errorf("unknown generator %q, choose one of: %s", generator, strings.Join(names, ", "))
+	}
+	if width < 3 || height < 3 {
+		return Level{}, fmt.Errorf("level must be at least 3x3, got %dx%d", width, height)
+	}
+	m := gen((height-1)/2, (width-1)/2)
+	m.Carve(0, 0, TopEdge)
+	m.Carve(m.Rows-1, m.Cols-1, BottomEdge)
+	return m.Level(storage), nil
+}
+
+// GenerateBinaryTree carves a maze by opening the wall either to the north or to the
+// east of every cell. Quick, but there are long corridors along the top and the
+// right side, and the paths all lean towards the top right.
+func GenerateBinaryTree(rows, cols int) *EdgeMaze {
+	m := NewEdgeMaze(rows, cols)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			var edges []Edge
+			if row > 0 {
+				edges = append(edges, TopEdge)
+			}
+			if col < cols-1 {
+				edges = append(edges, RightEdge)
+			}
+			if len(edges) > 0 {
+				m.Carve(row, col, edges[rand.Intn(len(edges))])
+			}
+		}
+	}
+	return m
+}
+
+// GenerateSidewinder carves a maze row by row. Each row is cut into runs of cells
+// joined from west to east, and every run is joined to the row above from one of
+// it's cells. The top row is one long corridor.
+func GenerateSidewinder(rows, cols int) *EdgeMaze {
+	m := NewEdgeMaze(rows, cols)
+	for row := 0; row < rows; row++ {
+		start := 0
+		for col := 0; col < cols; col++ {
+			closeRun := col == cols-1 || (row > 0 && rand.Intn(2) == 0)
+			if !closeRun {
+				m.Carve(row, col, RightEdge)
+				continue
+			}
+			if row > 0 {
+				m.Carve(row, start+rand.Intn(col-start+1), TopEdge)
+			}
+			start = col + 1
+		}
+	}
+	return m
+}
+
+// GeneratePrim grows a maze from a random cell, joining a random cell next to the
+// maze to it at every step. The mazes have lots of short dead ends.
+func GeneratePrim(rows, cols int) *EdgeMaze {
+	m := NewEdgeMaze(rows, cols)
+	if rows <= 0 || cols <= 0 {
+		return m
+	}
+
+	in := make([]bool, rows*cols)     // Cells in the maze
+	queued := make([]bool, rows*cols) // Cells in the frontier
+	var frontier [][2]int
+	add := func(row, col int) {
+		in[row*cols+col] = true
+		for _, e := range cellEdges {
+			r, c := m.neighbour(row, col, e)
+			if m.Contains(r, c) && !in[r*cols+c] && !queued[r*cols+c] {
+				queued[r*cols+c] = true
+				frontier = append(frontier, [2]int{r, c})
+			}
+		}
+	}
+	add(rand.Intn(rows), rand.Intn(cols))
+
+	for len(frontier) > 0 {
+		i := rand.Intn(len(frontier))
+		cell := frontier[i]
+		frontier[i] = frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+
+		var edges []Edge
+		for _, e := range cellEdges {
+			if r, c := m.neighbour(cell[0], cell[1], e); m.Contains(r, c) && in[r*cols+c] {
+				edges = append(edges, e)
+			}
+		}
+		m.Carve(cell[0], cell[1], edges[rand.Intn(len(edges))])
+		add(cell[0], cell[1])
+	}
+	return m
+}
+
+// GenerateKruskal knocks down the walls in random order, skipping the ones between
+// cells that are already connected. The mazes look much like the ones of GeneratePrim.
+func GenerateKruskal(rows, cols int) *EdgeMaze {
+	m := NewEdgeMaze(rows, cols)
+
+	type wall struct {
+		row, col int
+		edge     Edge
+	}
+	var walls []wall
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if row < rows-1 {
+				walls = append(walls, wall{row, col, BottomEdge})
+			}
+			if col < cols-1 {
+				walls = append(walls, wall{row, col, RightEdge})
+			}
+		}
+	}
+	rand.Shuffle(len(walls), func(i, j int) { walls[i], walls[j] = walls[j], walls[i] })
+
+	// Sets of connected cells, each cell points towards the root of it's set
+	parent := make([]int, rows*cols)
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	for _, w := range walls {
+		r, c := m.neighbour(w.row, w.col, w.edge)
+		a, b := find(w.row*cols+w.col), find(r*cols+c)
+		if a != b {
+			parent[a] = b
+			m.Carve(w.row, w.col, w.edge)
+		}
+	}
+	return m
+}
@@ -0,0 +1,63 @@
+package maze
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestEdgeMazeRoundTrip(t *testing.T) {
+	var names []string
+	for name := range EdgeGenerators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for seed := int64(1); seed <= 5; seed++ {
+			rand.Seed(seed)
+			m := EdgeGenerators[name](7, 12)
+			m.Carve(0, 0, TopEdge)
+			m.Carve(3, 0, LeftEdge)
+			m.Carve(m.Rows-1, m.Cols-1, BottomEdge)
+			for _, storage := range []Storage{DenseStorage, PackedStorage} {
+				level := m.Level(storage)
+				back, err := EdgeMazeFromLevel(level)
+				if err != nil {
+					t.Fatalf("%s seed %d, %s: %v", name, seed, storage, err)
+				}
+				if !reflect.DeepEqual(back, m) {
+					t.Errorf("%s seed %d, %s: maze converted back differs", name, seed, storage)
+				}
+
+				var text, again bytes.Buffer
+				if err := WriteLevel(&text, level); err != nil {
+					t.Fatal(err)
+				}
+				if err := WriteLevel(&again, back.Level(storage)); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(text.Bytes(), again.Bytes()) {
+					t.Errorf("%s seed %d, %s: level converted back differs:\n%s\nwant:\n%s", name, seed, storage, &again, &text)
+				}
+			}
+		}
+	}
+}
+
+func TestEdgeMazeFromLevelRefusesOtherLevels(t *testing.T) {
+	tests := []struct {
+		name, level string
+	}{
+		{"wall in a cell", walledIn},
+		{"even width", "######\n#    =\n######\n"},
+		{"opening not an exit", "##=##\n#   #\n## ##\n"},
+		{"two floors", twoFloors},
+	}
+	for _, test := range tests {
+		if _, err := EdgeMazeFromLevel(readTestLevel(t, test.level)); err == nil {
+			t.Errorf("%s: converted", test.name)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+// Package maze, placing the exits of a level
+package maze
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Edge is one or more edges of the level frame, or of a cell in an EdgeMaze
+type Edge int
+
+// Edges of the frame, they can be combined
+const (
+	TopEdge Edge = 1 << iota
+	RightEdge
+	BottomEdge
+	LeftEdge
+
+	AllEdges = TopEdge | RightEdge | BottomEdge | LeftEdge
+)
+
+var edgeNames = []string{"top", "right", "bottom", "left"}
+
+// ParseEdges parses a comma separated list of edges, eg. "top,left". "all" means
+// all of them.
+func ParseEdges(s string) (Edge, error) {
+	var edges Edge
+	for _, name := range strings.Split(s, ",") {
+		if name == "all" {
+			edges |= AllEdges
+			continue
+		}
+		found := false
+		for i, n := range edgeNames {
+			if n == name {
+				edges |= 1 << uint(i)
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown edge %q, expected top, right, bottom, left or all", name)
+		}
+	}
+	return edges, nil
+}
+
+// String returns the names of the edges, eg. "top,left"
+func (e Edge) String() string {
+	var names []string
+	for i, n := range edgeNames {
+		if e&(1<<uint(i)) != 0 {
+			names = append(names, n)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// ExitSpec describes the exits of a generated maze. The zero value asks for the
+// usual two exits, top left and bottom right.
+type ExitSpec struct {
+	Count     int        // Number of exits placed at random on the Edges
+	Edges     Edge       // Edges for the random exits, all of them if zero
+	Positions []Position // Exits at these positions, before the random ones
+}
+
+// ParseExitSpec parses an exit specification. It's either the number of random
+// exits, optionally followed by the edges for them, eg. "4" or "3:top,left", or a
+// list of positions separated by semicolons, eg. "0,5;19,30". Positions can also
+// have a floor: "row,col,floor".
+func ParseExitSpec(s string) (ExitSpec, error) {
+	var spec ExitSpec
+	if strings.Contains(s, ",") && !strings.Contains(s, ":") {
+		for _, p := range strings.Split(s, ";") {
+			var pos Position
+			if _, err := fmt.Sscanf(p, "%d,%d,%d", &pos.row, &pos.col, &pos.floor); err != nil {
+				pos.floor = 0
+				if _, err := fmt.Sscanf(p, "%d,%d", &pos.row, &pos.col); err != nil {
+					return ExitSpec{}, fmt.Errorf("bad exit position %q, expected row,col or row,col,floor", p)
+				}
+			}
+			spec.Positions = append(spec.Positions, pos)
+		}
+		return spec, nil
+	}
+
+	parts := strings.SplitN(s, ":", 2)
+	count, err := strconv.Atoi(parts[0])
+	if err != nil || count < 1 {
+		return ExitSpec{}, fmt.Errorf("bad exit count %q", parts[0])
+	}
+	spec.Count = count
+	if len(parts) > 1 {
+		if spec.Edges, err = ParseEdges(parts[1]); err != nil {
+			return ExitSpec{}, err
+		}
+	}
+	return spec, nil
+}
+
+// CreateExits places the exits given by the spec. Every exit is connected to the
+// maze, so if the maze itself has no disconnected rooms, all the exits can be
+// reached from each other. Random exits are kept apart from each other if there's
+// room for it, and only go where there's an opening straight in from the frame. An
+// error is returned if a position can't hold an exit or if there are not enough
+// places for the random exits.
+func (level *Level) CreateExits(spec ExitSpec) error {
+	for _, pos := range spec.Positions {
+		if err := level.CreateExit(pos); err != nil {
+			return err
+		}
+	}
+	if spec.Count == 0 {
+		return nil
+	}
+
+	edges := spec.Edges
+	if edges == 0 {
+		edges = AllEdges
+	}
+	var spots []Position
+	level.forEachTile(func(pos Position, t Tile) {
+		if !level.onEdges(pos, edges) || level.isExit(pos) {
+			return
+		}
+		if _, _, ok := level.frameOpening(pos); ok {
+			spots = append(spots, pos)
+		}
+	})
+	if len(spots) < spec.Count {
+		return fmt.Errorf("room for only %d exits on the %s, wanted %d", len(spots), edges, spec.Count)
+	}
+	rand.Shuffle(len(spots), func(i, j int) { spots[i], spots[j] = spots[j], spots[i] })
+
+	// First pick the spots that are not next to another exit, then fill up with the rest
+	wanted := spec.Count
+	picked := make(map[Position]bool)
+	for _, apart := range []bool{true, false} {
+		for _, pos := range spots {
+			if spec.Count == 0 {
+				return nil
+			}
+			if picked[pos] || (apart && level.nextToExit(pos)) {
+				continue
+			}
+			picked[pos] = true
+			if err := level.createFrameExit(pos); err != nil {
+				// The spots were checked for openings, but try the next one anyway
+				continue
+			}
+			spec.Count--
+		}
+	}
+	if spec.Count > 0 {
+		return fmt.Errorf("room for only %d exits on the %s, wanted %d", wanted-spec.Count, edges, wanted)
+	}
+	return nil
+}
+
+// CreateExit makes an exit at the position. On the frame the exit is connected to
+// the maze by ploughing straight in until an opening, inside the level it's ploughed
+// towards the nearest opening. Corners of the frame can't be exits.
+func (level *Level) CreateExit(pos Position) error {
+	if !level.WithinBounds(pos) {
+		return fmt.Errorf("exit %d,%d is outside the level", pos.row, pos.col)
+	}
+	if level.isExit(pos) {
+		return nil
+	}
+	if level.WithinFrame(pos) {
+		return level.createInnerExit(pos)
+	}
+	if level.onEdges(pos, AllEdges) {
+		return level.createFrameExit(pos)
+	}
+	return fmt.Errorf("exit %d,%d is in a corner of the level", pos.row, pos.col)
+}
+
+// createFrameExit creates an exit on the edge of the frame. Fails if there's no
+// opening straight in from the exit to connect it to.
+func (level *Level) createFrameExit(pos Position) error {
+	opening, dir, ok := level.frameOpening(pos)
+	if !ok {
+		return fmt.Errorf("exit %d,%d has no opening to connect to", pos.row, pos.col)
+	}
+
+	// Plough through the level to remove any obstacles that might be blocking the exit
+	level.setTile(pos, Tile{EmptyTile, ' '})
+	level.Exits = append(level.Exits, pos)
+	for pos = AddDirection(pos, dir); pos != opening; pos = AddDirection(pos, dir) {
+		level.setTile(pos, Tile{EmptyTile, ' '})
+	}
+	return nil
+}
+
+// frameOpening finds the first opening straight in from the position on the edge of
+// the frame, and the direction in. Not ok if there's only walls up to the other side.
+func (level *Level) frameOpening(pos Position) (Position, Direction, bool) {
+	var dir Direction
+	switch {
+	case pos.row == 0:
+		dir = Direction{xd: 0, yd: 1}
+	case pos.col == 0:
+		dir = Direction{xd: 1, yd: 0}
+	case pos.row == level.height-1:
+		dir = Direction{xd: 0, yd: -1}
+	case pos.col == level.width-1:
+		dir = Direction{xd: -1, yd: 0}
+	default:
+		panic("Can't plough the exit!")
+	}
+
+	opening := AddDirection(pos, dir)
+	for level.WithinFrame(opening) && !level.CanMove(opening) {
+		opening = AddDirection(opening, dir)
+	}
+	return opening, dir, level.WithinFrame(opening)
+}
+
+// createInnerExit creates an exit inside the frame, ploughing to the closest
+// opening if it's in a wall. Fails if there's no opening in any direction.
+func (level *Level) createInnerExit(pos Position) error {
+	if level.IsWalkable(pos) {
+		level.setTile(pos, Tile{EmptyTile, ' '})
+		level.Exits = append(level.Exits, pos)
+		return nil
+	}
+
+	best, bestSteps := Direction{}, 0
+	for _, dir := range ValidDirections {
+		steps := 1
+		next := AddDirection(pos, dir)
+		for level.WithinFrame(next) && !level.CanMove(next) {
+			next = AddDirection(next, dir)
+			steps++
+		}
+		if level.WithinFrame(next) && (bestSteps == 0 || steps < bestSteps) {
+			best, bestSteps = dir, steps
+		}
+	}
+	if bestSteps == 0 {
+		return fmt.Errorf("exit %d,%d has no opening to connect to", pos.row, pos.col)
+	}
+
+	level.setTile(pos, Tile{EmptyTile, ' '})
+	level.Exits = append(level.Exits, pos)
+	for next := AddDirection(pos, best); bestSteps > 1; next = AddDirection(next, best) {
+		level.setTile(next, Tile{EmptyTile, ' '})
+		bestSteps--
+	}
+	return nil
+}
+
+// onEdges tells if the position is on the given edges of the frame, not counting
+// the corners
+func (level Level) onEdges(pos Position, edges Edge) bool {
+	if !level.WithinBounds(pos) {
+		return false
+	}
+	rowInside := pos.row > 0 && pos.row < level.height-1
+	colInside := pos.col > 0 && pos.col < level.width-1
+	switch {
+	case pos.row == 0 && colInside:
+		return edges&TopEdge != 0
+	case pos.row == level.height-1 && colInside:
+		return edges&BottomEdge != 0
+	case pos.col == 0 && rowInside:
+		return edges&LeftEdge != 0
+	case pos.col == level.width-1 && rowInside:
+		return edges&RightEdge != 0
+	}
+	return false
+}
+
+// isExit tells if there's an exit at the position
+func (level Level) isExit(pos Position) bool {
+	for _, exit := range level.Exits {
+		if exit == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// nextToExit tells if there's an exit right next to the position
+func (level Level) nextToExit(pos Position) bool {
+	for _, dir := range ValidDirections {
+		if level.isExit(AddDirection(pos, dir)) {
+			return true
+		}
+	}
+	return false
+}
+
+// NearestExit finds the exit that is the cheapest to walk to from the position,
+// not counting an exit at the position itself. Returns false if no exit can be
+// reached.
+func (level Level) NearestExit(from Position) (Position, bool) {
+	finish := searchPath(level, from, func(pos Position) bool {
+		return pos != from && level.isExit(pos)
+	})
+	if finish == nil {
+		return Position{}, false
+	}
+	return finish.pos, true
+}
@@ -0,0 +1,30 @@
+package maze
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// walledIn has an opening straight in from only 6 spots of the frame, the two
+// columns and the row of the corridor
+const walledIn = `#######
+#  ####
+#######
+`
+
+func TestCreateExitsOnlyWhereThereIsAnOpening(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		rand.Seed(seed)
+		level := readTestLevel(t, walledIn)
+		if err := level.CreateExits(ExitSpec{Count: 6}); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if len(level.Exits) != 6 {
+			t.Fatalf("seed %d: got %d exits, want 6", seed, len(level.Exits))
+		}
+	}
+	level := readTestLevel(t, walledIn)
+	if err := level.CreateExits(ExitSpec{Count: 7}); err == nil {
+		t.Error("7 exits fit where there's room for 6")
+	}
+}
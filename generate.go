@@ -2,7 +2,9 @@
 package maze
 
 import (
+	"fmt"
 	"math/rand"
+	"sort"
 )
 
 const (
@@ -13,23 +15,194 @@ const (
 // GenerateRandomMaze generates a maze that does not contain disconnected rooms.
 // Eg. if we place an actor to an empty spot on the level, it should be able to
 // navigate to every other empty spot.
-// There will be 2 exits one in the top left and another in the bottom right.
-func GenerateRandomMaze(width, height int) Level {
-	level := MakeEmptyLevel(width, height)
+// There will be 2 exits one in the top left and another in the bottom right. Fails
+// like GenerateMultiFloorMaze if an exit has no opening to connect to.
+func GenerateRandomMaze(width, height int) (Level, error) {
+	return GenerateMultiFloorMaze(width, height, 1, DenseStorage)
+}
 
-	// Fill the inside of the level with tiles, we're gonna plough into it to make a maze.
-	for row, tileRow := range level.tiles {
-		for col := range tileRow {
-			if row > 0 && col > 0 && row < level.height-1 && col < level.width-1 {
-				level.tiles[row][col] = Tile{WallTile, WallBlock}
+// GenerateMultiFloorMaze generates a maze spanning several floors. Each floor is a
+// maze of it's own, connected to the floor above it with stairs, so that every empty
+// spot can still be reached from every other one. The entrance is in the top left
+// of the ground floor and the exit in the bottom right of the top floor. Fails if
+// the floors are too small to fit the stairs. The tiles are kept in the storage.
+func GenerateMultiFloorMaze(width, height, floors int, storage Storage) (Level, error) {
+	level, err := generateFloors(width, height, floors, storage)
+	if err != nil {
+		return level, err
+	}
+	if err := level.CreateHorizontalExit(Position{row: 0, col: 1}); err != nil {
+		return level, err
+	}
+	err = level.CreateHorizontalExit(Position{row: height - 1, col: width - 2, floor: floors - 1})
+	return level, err
+}
+
+// GenerateMazeWithExits generates a maze like GenerateMultiFloorMaze, but with the
+// exits placed as specified. The zero ExitSpec gives the usual exits.
+func GenerateMazeWithExits(width, height, floors int, exits ExitSpec, storage Storage) (Level, error) {
+	if exits.Count == 0 && len(exits.Positions) == 0 {
+		return GenerateMultiFloorMaze(width, height, floors, storage)
+	}
+	level, err := generateFloors(width, height, floors, storage)
+	if err != nil {
+		return level, err
+	}
+	err = level.CreateExits(exits)
+	return level, err
+}
+
+// generateFloors generates the floors of a maze and the stairs between them, without exits
+func generateFloors(width, height, floors int, storage Storage) (Level, error) {
+	level := makeEmptyLevel(width, height, floors, storage)
+	for floor := 0; floor < floors; floor++ {
+		carveFloor(&level, floor)
+	}
+	for floor := 0; floor < floors-1; floor++ {
+		if err := placeStairs(&level, floor); err != nil {
+			return level, err
+		}
+	}
+	return level, nil
+}
+
+// GenerateMaskedMaze generates a maze in the shape of the mask. The level is the size
+// of the mask with a frame around it. Tiles masked out are solid walls, or void if
+// void is set, apart from a wall around the maze.
+//
+// The exits are placed on the boundary of the mask, one towards the top left and the
+// other towards the bottom right. If the mask is in several disconnected parts, like
+// the letters of a text, each part gets a maze and two exits of it's own, the first
+// two exits being on the largest part. Fails if a part has no room for two exits.
+// The tiles are kept in the storage.
+func GenerateMaskedMaze(mask *Mask, void bool, storage Storage) (Level, error) {
+	width, height := mask.Size()
+	level := makeEmptyLevel(width+2, height+2, 1, storage)
+	inside := func(pos Position) bool {
+		return mask.On(pos.row-1, pos.col-1)
+	}
+	level.forEachTile(func(pos Position, t Tile) {
+		level.setTile(pos, Tile{WallTile, WallBlock})
+	})
+
+	// Carve a maze into each part of the mask, the largest first
+	parts := regions(level, inside)
+	for _, part := range parts {
+		start := part[rand.Intn(len(part))]
+		level.setTile(start, Tile{EmptyTile, ' '})
+		carve(&level, start, inside)
+	}
+
+	for _, part := range parts {
+		if err := level.createBoundaryExits(part, inside); err != nil {
+			return level, err
+		}
+	}
+
+	if void {
+		// Clear the walls that are not next to the maze
+		level.forEachTile(func(pos Position, t Tile) {
+			if inside(pos) || t.tileType != WallTile {
+				return
 			}
+			for row := pos.row - 1; row <= pos.row+1; row++ {
+				for col := pos.col - 1; col <= pos.col+1; col++ {
+					near := Position{row: row, col: col}
+					if level.WithinBounds(near) && level.IsWalkable(near) {
+						return
+					}
+				}
+			}
+			level.setTile(pos, Tile{VoidTile, ' '})
+		})
+	}
+	return level, nil
+}
+
+// regions finds the connected regions of the positions that are inside, largest first
+func regions(level Level, inside func(pos Position) bool) [][]Position {
+	var parts [][]Position
+	seen := make([]bool, level.width*level.height)
+	level.forEachTile(func(pos Position, t Tile) {
+		if !inside(pos) || seen[level.index(pos)] {
+			return
+		}
+		seen[level.index(pos)] = true
+		part := []Position{pos}
+		for i := 0; i < len(part); i++ {
+			for _, dir := range ValidDirections {
+				next := AddDirection(part[i], dir)
+				if inside(next) && !seen[level.index(next)] {
+					seen[level.index(next)] = true
+					part = append(part, next)
+				}
+			}
+		}
+		parts = append(parts, part)
+	})
+	sort.SliceStable(parts, func(i, j int) bool { return len(parts[i]) > len(parts[j]) })
+	return parts
+}
+
+// createBoundaryExits opens two exits in the walls around the part of the mask, the
+// closest one to the top left corner and the closest one to the bottom right. Fails
+// if the maze of the part doesn't reach the boundary in two places.
+func (level *Level) createBoundaryExits(part []Position, inside func(pos Position) bool) error {
+	var exits []Position
+	seen := make(map[Position]bool)
+	for _, pos := range part {
+		if !level.IsWalkable(pos) {
+			continue
+		}
+		for _, dir := range ValidDirections {
+			wall := AddDirection(pos, dir)
+			if !inside(wall) && !seen[wall] && level.WithinBounds(wall) && level.tile(wall).tileType == WallTile {
+				seen[wall] = true
+				exits = append(exits, wall)
+			}
+		}
+	}
+	if len(exits) < 2 {
+		pos := part[0]
+		return fmt.Errorf("no room for two exits on the part of the mask at %d,%d", pos.row-1, pos.col-1)
+	}
+
+	first, last := exits[0], exits[0]
+	for _, pos := range exits {
+		if pos.row+pos.col < first.row+first.col {
+			first = pos
+		}
+		if pos.row+pos.col > last.row+last.col {
+			last = pos
+		}
+	}
+	if last == first {
+		// All on a diagonal, first is the first one found
+		last = exits[1]
+	}
+	for _, pos := range []Position{first, last} {
+		level.setTile(pos, Tile{EmptyTile, ' '})
+		level.Exits = append(level.Exits, pos)
+	}
+	return nil
+}
+
+// carveFloor ploughs a maze into one floor of the level
+func carveFloor(level *Level, floor int) {
+	// Fill the inside of the level with tiles, we're gonna plough into it to make a maze.
+	for row := 1; row < level.height-1; row++ {
+		for col := 1; col < level.width-1; col++ {
+			level.setTile(Position{row: row, col: col, floor: floor}, Tile{WallTile, WallBlock})
 		}
 	}
 
 	// Generate a path from top left to bottom right.
-	startPos := Position{row: 0, col: 1}
-	exitPos := Position{row: level.height - 1, col: level.width - 2}
+	carve(level, Position{row: 0, col: 1, floor: floor}, level.WithinFrame)
+}
 
+// carve ploughs corridors into the walls, starting from startPos and staying on the
+// positions that are inside.
+func carve(level *Level, startPos Position, inside func(pos Position) bool) {
 	// turnStack stores all the turning points, so that we can pop a previous location
 	// when we run into dead end.
 	turnStack := make([]Position, 0)
@@ -55,13 +228,13 @@ func GenerateRandomMaze(width, height int) Level {
 				newPos := AddDirection(pos, dir)
 
 				// Stop walking if we've been here before
-				if !level.WithinFrame(newPos) || level.IsWalkable(newPos) {
+				if !inside(newPos) || level.IsWalkable(newPos) {
 					break
 				}
-				if !hasEnoughWalls(level, newPos, pos) {
+				if !hasEnoughWalls(*level, newPos, pos) {
 					break
 				}
-				level.tiles[newPos.row][newPos.col] = Tile{EmptyTile, ' '}
+				level.setTile(newPos, Tile{EmptyTile, ' '})
 				pos = newPos
 				steps++
 			}
@@ -78,10 +251,39 @@ func GenerateRandomMaze(width, height int) Level {
 			turnStack = turnStack[:len(turnStack)-1]
 		}
 	}
+}
 
-	level.CreateHorizontalExit(startPos)
-	level.CreateHorizontalExit(exitPos)
-	return level
+// placeStairs connects a floor with the one above it with a staircase in a random
+// spot that is empty on both floors. If the floors have no empty spots in common,
+// a wall next to a corridor on the floor above is knocked down for the stairs. Fails
+// if there's neither, on floors too small for a maze.
+func placeStairs(level *Level, floor int) error {
+	var spots, walls []Position
+	for row := 1; row < level.height-1; row++ {
+		for col := 1; col < level.width-1; col++ {
+			pos := Position{row: row, col: col, floor: floor}
+			above := Position{row: row, col: col, floor: floor + 1}
+			if level.tile(pos).tileType != EmptyTile {
+				continue
+			}
+			if level.tile(above).tileType == EmptyTile {
+				spots = append(spots, pos)
+			} else if level.tile(above).tileType == WallTile && level.degree(above) > 0 {
+				walls = append(walls, pos)
+			}
+		}
+	}
+	if len(spots) == 0 {
+		spots = walls
+	}
+	if len(spots) == 0 {
+		return fmt.Errorf("no room for the stairs from floor %d to floor %d on a %dx%d level", floor, floor+1, level.width, level.height)
+	}
+
+	pos := spots[rand.Intn(len(spots))]
+	level.setTile(pos, Tile{StairsUpTile, StairsUp})
+	level.setTile(AddDirection(pos, Direction{zd: 1}), Tile{StairsDownTile, StairsDown})
+	return nil
 }
 
 // hasEnoughWalls validates that there is enough surrounding space around the position.
@@ -108,48 +310,23 @@ func hasEnoughWalls(level Level, pos Position, origin Position) bool {
 
 // MakeEmptyLevel generates a level frame, borders, corners. etc.
 func MakeEmptyLevel(width, height int) Level {
-	level := Level{width: width, height: height}
-	level.tiles = make([][]Tile, height)
-	for row := range level.tiles {
-		level.tiles[row] = make([]Tile, width)
-		for col := range level.tiles[row] {
-			var t Tile
-			if row == 0 || row == height-1 {
-				t = Tile{WallTile, WallBlock}
-			} else if col == 0 || col == width-1 {
-				t = Tile{WallTile, WallBlock}
-			} else {
-				t = Tile{EmptyTile, ' '}
-			}
-			level.tiles[row][col] = t
+	return makeEmptyLevel(width, height, 1, DenseStorage)
+}
+
+// makeEmptyLevel generates the frames for each floor of a level, kept in the storage
+func makeEmptyLevel(width, height, floors int, storage Storage) Level {
+	level := Level{width: width, height: height, floors: floors}
+	level.tiles = storage.newTiles(width, height, floors, Tile{EmptyTile, ' '})
+	level.forEachTile(func(pos Position, t Tile) {
+		if !level.WithinFrame(pos) {
+			level.setTile(pos, Tile{WallTile, WallBlock})
 		}
-	}
+	})
 	return level
 }
 
-// CreateHorizontalExit creates an exit in the horizontal frame of the level.
-func (level *Level) CreateHorizontalExit(pos Position) {
-	level.tiles[pos.row][pos.col] = Tile{EmptyTile, ' '}
-	level.Exits = append(level.Exits, pos)
-
-	// Plough through the level to remove any obstacles that might be blocking the exit
-	dir := Direction{xd: 0, yd: +1}
-
-	switch {
-	case pos.row == 0:
-		dir = Direction{xd: 0, yd: 1}
-	case pos.col == 0:
-		dir = Direction{xd: 1, yd: 0}
-	case pos.row == level.height-1:
-		dir = Direction{xd: 0, yd: -1}
-	case pos.col == level.width-1:
-		dir = Direction{xd: -1, yd: 0}
-	default:
-		panic("Can't plough the exit!")
-	}
-
-	// Plough through the level until we hit an opening
-	for pos = AddDirection(pos, dir); level.WithinFrame(pos) && !level.CanMove(pos); pos = AddDirection(pos, dir) {
-		level.tiles[pos.row][pos.col] = Tile{EmptyTile, ' '}
-	}
+// CreateHorizontalExit creates an exit in the horizontal frame of the level. Fails
+// if there's no opening straight in from the exit.
+func (level *Level) CreateHorizontalExit(pos Position) error {
+	return level.createFrameExit(pos)
 }
@@ -0,0 +1,194 @@
+// Package maze, mazes carved into grids of any shape.
+//
+// Level models the maze as a matrix of tiles where walls take up tiles of their
+// own. That only works for square grids, so mazes on other grids are built from
+// cells whose walls are the sides of a polygon, with passages knocked through the
+// walls between neighbouring cells.
+package maze
+
+import (
+	"math/rand"
+)
+
+// Cell identifies a cell on a Grid, the cells are numbered from 0 to Grid.Size()-1
+type Cell int
+
+// NoCell is the neighbour beyond the outer edge of the grid
+const NoCell Cell = -1
+
+// Point is a location on the plane that the grid is drawn on. The unit is roughly
+// the size of a cell.
+type Point struct {
+	X, Y float64
+}
+
+// Side is one side of a cell's polygon, shared with at most one neighbouring cell.
+type Side struct {
+	From, To Point
+	Neighbor Cell // NoCell if the side is on the outer edge of the grid
+}
+
+// Grid describes the shape of the cells and which cells neighbour each other.
+type Grid interface {
+	// Size returns the number of cells
+	Size() int
+	// Sides returns the sides of the cell's polygon
+	Sides(c Cell) []Side
+	// Center returns the middle of the cell
+	Center(c Cell) Point
+	// Bounds returns the width and height of the area covered by the grid
+	Bounds() (float64, float64)
+}
+
+// Neighbors returns the cells next to c on the grid
+func Neighbors(g Grid, c Cell) []Cell {
+	var cells []Cell
+	for _, side := range g.Sides(c) {
+		if side.Neighbor != NoCell {
+			cells = append(cells, side.Neighbor)
+		}
+	}
+	return cells
+}
+
+// GridMaze is a maze carved into a Grid by linking neighbouring cells with passages
+type GridMaze struct {
+	Grid  Grid
+	links map[[2]Cell]bool
+}
+
+// NewGridMaze returns a maze with no passages, every cell is walled in.
+func NewGridMaze(g Grid) *GridMaze {
+	return &GridMaze{Grid: g, links: make(map[[2]Cell]bool)}
+}
+
+func linkKey(a, b Cell) [2]Cell {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]Cell{a, b}
+}
+
+// Link knocks down the wall between two neighbouring cells
+func (m *GridMaze) Link(a, b Cell) {
+	m.links[linkKey(a, b)] = true
+}
+
+// Linked tells if there's a passage between the cells
+func (m *GridMaze) Linked(a, b Cell) bool {
+	return m.links[linkKey(a, b)]
+}
+
+// Links returns the cells that can be reached from c in one step
+func (m *GridMaze) Links(c Cell) []Cell {
+	var cells []Cell
+	for _, n := range Neighbors(m.Grid, c) {
+		if m.Linked(c, n) {
+			cells = append(cells, n)
+		}
+	}
+	return cells
+}
+
+// GenerateGridMaze carves a maze into the grid with the recursive backtracker. Every
+// cell can be reached from every other cell by exactly one path.
+func GenerateGridMaze(g Grid) *GridMaze {
+	m := NewGridMaze(g)
+	if g.Size() == 0 {
+		return m
+	}
+
+	visited := make([]bool, g.Size())
+	start := Cell(rand.Intn(g.Size()))
+	visited[start] = true
+	stack := []Cell{start}
+
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+
+		var unvisited []Cell
+		for _, n := range Neighbors(g, c) {
+			if !visited[n] {
+				unvisited = append(unvisited, n)
+			}
+		}
+		if len(unvisited) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		next := unvisited[rand.Intn(len(unvisited))]
+		m.Link(c, next)
+		visited[next] = true
+		stack = append(stack, next)
+	}
+	return m
+}
+
+// Solve returns the shortest path of cells from one cell to another using BFS, nil if
+// there's no way through.
+func (m *GridMaze) Solve(from, to Cell) []Cell {
+	parent := make(map[Cell]Cell)
+	parent[from] = NoCell
+	queue := []Cell{from}
+
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+
+		if c == to {
+			var path []Cell
+			for ; c != NoCell; c = parent[c] {
+				path = append([]Cell{c}, path...)
+			}
+			return path
+		}
+
+		for _, n := range m.Links(c) {
+			if _, seen := parent[n]; !seen {
+				parent[n] = c
+				queue = append(queue, n)
+			}
+		}
+	}
+	return nil
+}
+
+// SquareGrid is a grid of square cells, 4 neighbours each
+type SquareGrid struct {
+	Rows, Cols int
+}
+
+// Size returns the number of cells
+func (g SquareGrid) Size() int {
+	return g.Rows * g.Cols
+}
+
+func (g SquareGrid) cell(row, col int) Cell {
+	if row < 0 || col < 0 || row >= g.Rows || col >= g.Cols {
+		return NoCell
+	}
+	return Cell(row*g.Cols + col)
+}
+
+// Sides returns the north, east, south and west walls of the cell
+func (g SquareGrid) Sides(c Cell) []Side {
+	row, col := int(c)/g.Cols, int(c)%g.Cols
+	x, y := float64(col), float64(row)
+	return []Side{
+		{Point{x, y}, Point{x + 1, y}, g.cell(row-1, col)},
+		{Point{x + 1, y}, Point{x + 1, y + 1}, g.cell(row, col+1)},
+		{Point{x + 1, y + 1}, Point{x, y + 1}, g.cell(row+1, col)},
+		{Point{x, y + 1}, Point{x, y}, g.cell(row, col-1)},
+	}
+}
+
+// Center returns the middle of the cell
+func (g SquareGrid) Center(c Cell) Point {
+	return Point{float64(int(c)%g.Cols) + 0.5, float64(int(c)/g.Cols) + 0.5}
+}
+
+// Bounds returns the width and height of the grid
+func (g SquareGrid) Bounds() (float64, float64) {
+	return float64(g.Cols), float64(g.Rows)
+}
@@ -0,0 +1,72 @@
+// Package maze, drawing grid mazes
+package maze
+
+import (
+	"math"
+)
+
+// RenderGrid draws the grid maze and a path through it as text. The walls are
+// rasterized onto the character cells, scale is the number of text rows per unit of
+// the grid. Characters are about twice as tall as they are wide, so there are twice
+// as many columns per unit.
+func RenderGrid(m *GridMaze, path []Cell, banner string, scale float64, r Renderer) {
+	width, height := m.Grid.Bounds()
+	canvas := make([][]rune, int(math.Ceil(height*scale))+1)
+	for row := range canvas {
+		canvas[row] = make([]rune, int(math.Ceil(2*width*scale))+1)
+		for col := range canvas[row] {
+			canvas[row][col] = ' '
+		}
+	}
+
+	// Plots a line onto the canvas by sampling it twice per character
+	plot := func(from, to Point, c rune) {
+		x0, y0 := 2*from.X*scale, from.Y*scale
+		x1, y1 := 2*to.X*scale, to.Y*scale
+		steps := int(2*math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+		for i := 0; i <= steps; i++ {
+			t := float64(i) / float64(steps)
+			row := int(math.Round(y0 + t*(y1-y0)))
+			col := int(math.Round(x0 + t*(x1-x0)))
+			if row >= 0 && row < len(canvas) && col >= 0 && col < len(canvas[row]) {
+				canvas[row][col] = c
+			}
+		}
+	}
+
+	for i := 1; i < len(path); i++ {
+		plot(m.Grid.Center(path[i-1]), m.Grid.Center(path[i]), '.')
+	}
+	forEachWall(m, func(side Side) {
+		plot(side.From, side.To, WallBlock)
+	})
+	if len(path) > 0 {
+		plot(m.Grid.Center(path[0]), m.Grid.Center(path[0]), '@')
+		plot(m.Grid.Center(path[len(path)-1]), m.Grid.Center(path[len(path)-1]), '=')
+	}
+
+	r.Reset()
+	for _, c := range banner {
+		r.PutChar(c)
+	}
+	r.NextLine()
+	for _, row := range canvas {
+		for _, c := range row {
+			r.PutChar(c)
+		}
+		r.NextLine()
+	}
+	r.Flush()
+}
+
+// forEachWall calls f for every wall standing in the maze, once per wall
+func forEachWall(m *GridMaze, f func(side Side)) {
+	for c := Cell(0); int(c) < m.Grid.Size(); c++ {
+		for _, side := range m.Grid.Sides(c) {
+			// Walls between cells are seen from both sides, draw them from the lower numbered one
+			if side.Neighbor == NoCell || (c < side.Neighbor && !m.Linked(c, side.Neighbor)) {
+				f(side)
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package maze, heatmaps of the visits of the actors
+package maze
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// heatGlyphs are drawn on the visited tiles in text, from the fewest visits to the most
+var heatGlyphs = []rune{'.', ':', '+', '*', '%'}
+
+// heatColors are the backgrounds of the visited tiles in colour, from cold to hot
+var heatColors = []Color{ColorBlue, ColorCyan, ColorGreen, ColorYellow, ColorRed}
+
+// Heat is the number of visits the actors have made to each position, see Actor.Visits
+type Heat struct {
+	Visits map[Position]int
+	Max    int // The most visits to any position
+}
+
+// LevelHeat adds up the visits of all the actors on the level
+func LevelHeat(level Level) Heat {
+	heat := Heat{Visits: make(map[Position]int)}
+	for _, actor := range level.Actors {
+		for pos, n := range actor.Visits {
+			heat.Visits[pos] += n
+			if heat.Visits[pos] > heat.Max {
+				heat.Max = heat.Visits[pos]
+			}
+		}
+	}
+	return heat
+}
+
+// Share returns how hot the position is, from 0 for a single visit (or none) to 1
+// for the most visited. The scale is logarithmic, so that a few places walked over
+// and over again don't make everything else look cold.
+func (h Heat) Share(pos Position) float64 {
+	n := h.Visits[pos]
+	if n <= 1 || h.Max <= 1 {
+		return 0
+	}
+	return math.Log(float64(n)) / math.Log(float64(h.Max))
+}
+
+// step returns the position on a scale of n steps, -1 if the position wasn't visited
+func (h Heat) step(pos Position, n int) int {
+	if h.Visits[pos] == 0 {
+		return -1
+	}
+	i := int(h.Share(pos) * float64(n))
+	if i >= n {
+		i = n - 1
+	}
+	return i
+}
+
+// glyph returns the heatmap glyph of the position and whether it was visited at all.
+// Without a heatmap nothing was.
+func (h *Heat) glyph(pos Position) (glyph, bool) {
+	if h == nil {
+		return glyph{}, false
+	}
+	i := h.step(pos, len(heatGlyphs))
+	if i < 0 {
+		return glyph{}, false
+	}
+	return glyph{c: heatGlyphs[i], color: ColorBlack, bg: heatColors[i]}, true
+}
+
+// RGBA returns the colour of the position on a gradient from blue through green to
+// red, white if the position wasn't visited
+func (h Heat) RGBA(pos Position) color.RGBA {
+	if h.Visits[pos] == 0 {
+		return color.RGBA{255, 255, 255, 255}
+	}
+	// Hue from 240 (blue) down to 0 (red), full saturation and value
+	hue := 240 * (1 - h.Share(pos)) / 60
+	x := uint8(255 * (1 - math.Abs(math.Mod(hue, 2)-1)))
+	switch int(hue) {
+	case 0:
+		return color.RGBA{255, x, 0, 255}
+	case 1:
+		return color.RGBA{x, 255, 0, 255}
+	case 2:
+		return color.RGBA{0, 255, x, 255}
+	}
+	return color.RGBA{0, x, 255, 255}
+}
+
+// WriteHeatmapSVG draws the level as an SVG image like WriteSVG, but with the tiles
+// coloured by the number of times the actors visited them instead of their paths.
+func WriteHeatmapSVG(w io.Writer, level Level) error {
+	if level.Chunked() {
+		return errChunked
+	}
+	heat := LevelHeat(level)
+	px := func(v int) int { return svgMargin + v*svgTile }
+	x := func(pos Position) int { return px(pos.floor*(level.width+1) + pos.col) }
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\">\n",
+		(level.floors*(level.width+1)-1)*svgTile+2*svgMargin, level.height*svgTile+2*svgMargin)
+	fmt.Fprintf(out, "<rect width=\"100%%\" height=\"100%%\" fill=\"white\"/>\n")
+
+	level.forEachTile(func(pos Position, tile Tile) {
+		fill := ""
+		switch {
+		case tile.tileType == WallTile:
+			fill = "black"
+		case heat.Visits[pos] > 0:
+			c := heat.RGBA(pos)
+			fill = fmt.Sprintf("rgb(%d,%d,%d)", c.R, c.G, c.B)
+		case tile.tileType == StairsUpTile || tile.tileType == StairsDownTile:
+			fill = "grey"
+		default:
+			return
+		}
+		fmt.Fprintf(out, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n", x(pos), px(pos.row), svgTile, svgTile, fill)
+	})
+
+	for _, actor := range level.Actors {
+		fmt.Fprintf(out, "<text x=\"%d\" y=\"%d\" font-size=\"%d\" font-family=\"monospace\" text-anchor=\"middle\" fill=\"%s\">%s</text>\n",
+			x(actor.CurrPos)+svgTile/2, px(actor.CurrPos.row+1)-1, svgTile, svgColor(actor.Color, "black"), svgEscape(actor.Character))
+	}
+
+	fmt.Fprintf(out, "</svg>\n")
+	return out.Flush()
+}
+
+// pngTile is the number of pixels per tile in the PNG images
+const pngTile = 4
+
+// WriteHeatmapPNG draws the level as a PNG image with the tiles coloured by the
+// number of times the actors visited them. The walls are black and the floors are
+// side by side.
+func WriteHeatmapPNG(w io.Writer, level Level) error {
+	if level.Chunked() {
+		return errChunked
+	}
+	heat := LevelHeat(level)
+	img := image.NewRGBA(image.Rect(0, 0, (level.floors*(level.width+1)-1)*pngTile, level.height*pngTile))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+
+	level.forEachTile(func(pos Position, tile Tile) {
+		c := heat.RGBA(pos)
+		switch {
+		case tile.tileType == WallTile:
+			c = color.RGBA{0, 0, 0, 255}
+		case heat.Visits[pos] == 0 && (tile.tileType == StairsUpTile || tile.tileType == StairsDownTile):
+			c = color.RGBA{128, 128, 128, 255}
+		}
+		left := (pos.floor*(level.width+1) + pos.col) * pngTile
+		for y := pos.row * pngTile; y < (pos.row+1)*pngTile; y++ {
+			for x := left; x < left+pngTile; x++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	})
+	return png.Encode(w, img)
+}
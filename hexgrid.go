@@ -0,0 +1,135 @@
+// Package maze, grids of hexagons and triangles
+package maze
+
+import (
+	"math"
+)
+
+// HexGrid is a grid of pointy topped hexagons with 6 neighbours each. Every odd row
+// is shifted right by half a cell.
+type HexGrid struct {
+	Rows, Cols int
+}
+
+// Size returns the number of cells
+func (g HexGrid) Size() int {
+	return g.Rows * g.Cols
+}
+
+func (g HexGrid) cell(row, col int) Cell {
+	if row < 0 || col < 0 || row >= g.Rows || col >= g.Cols {
+		return NoCell
+	}
+	return Cell(row*g.Cols + col)
+}
+
+// Center returns the middle of the cell. The hexagons have a radius of 1.
+func (g HexGrid) Center(c Cell) Point {
+	row, col := int(c)/g.Cols, int(c)%g.Cols
+	return Point{
+		X: math.Sqrt(3) * (float64(col) + 0.5*float64(row&1) + 0.5),
+		Y: 1.5*float64(row) + 1,
+	}
+}
+
+// Sides returns the east, south east, south west, west, north west and north east
+// walls of the cell
+func (g HexGrid) Sides(c Cell) []Side {
+	row, col := int(c)/g.Cols, int(c)%g.Cols
+	center := g.Center(c)
+
+	// Columns of the diagonal neighbours depend on whether the row is shifted
+	left, right := col-1, col
+	if row&1 == 1 {
+		left, right = col, col+1
+	}
+	neighbors := [6]Cell{
+		g.cell(row, col+1),
+		g.cell(row+1, right),
+		g.cell(row+1, left),
+		g.cell(row, col-1),
+		g.cell(row-1, left),
+		g.cell(row-1, right),
+	}
+
+	var corners [6]Point
+	for i := range corners {
+		angle := math.Pi / 180 * float64(60*i-30)
+		corners[i] = Point{center.X + math.Cos(angle), center.Y + math.Sin(angle)}
+	}
+
+	sides := make([]Side, 6)
+	for i := range sides {
+		sides[i] = Side{corners[i], corners[(i+1)%6], neighbors[i]}
+	}
+	return sides
+}
+
+// Bounds returns the width and height of the grid
+func (g HexGrid) Bounds() (float64, float64) {
+	width := math.Sqrt(3) * float64(g.Cols)
+	if g.Rows > 1 {
+		width += math.Sqrt(3) / 2
+	}
+	return width, 1.5*float64(g.Rows) + 0.5
+}
+
+// TriangleGrid is a grid of triangles with 3 neighbours each. The triangles point up
+// and down in turns, starting with an upright one in the top left corner.
+type TriangleGrid struct {
+	Rows, Cols int
+}
+
+// triangleHeight is the height of a triangle with sides of 1
+var triangleHeight = math.Sqrt(3) / 2
+
+// Size returns the number of cells
+func (g TriangleGrid) Size() int {
+	return g.Rows * g.Cols
+}
+
+func (g TriangleGrid) cell(row, col int) Cell {
+	if row < 0 || col < 0 || row >= g.Rows || col >= g.Cols {
+		return NoCell
+	}
+	return Cell(row*g.Cols + col)
+}
+
+func (g TriangleGrid) upright(row, col int) bool {
+	return (row+col)%2 == 0
+}
+
+// Center returns the middle of the triangle
+func (g TriangleGrid) Center(c Cell) Point {
+	row, col := int(c)/g.Cols, int(c)%g.Cols
+	x := 0.5*float64(col) + 0.5
+	if g.upright(row, col) {
+		return Point{x, triangleHeight * (float64(row) + 2.0/3)}
+	}
+	return Point{x, triangleHeight * (float64(row) + 1.0/3)}
+}
+
+// Sides returns the walls of the triangle: left, right and the horizontal one
+func (g TriangleGrid) Sides(c Cell) []Side {
+	row, col := int(c)/g.Cols, int(c)%g.Cols
+	left, mid, right := 0.5*float64(col), 0.5*float64(col)+0.5, 0.5*float64(col)+1
+	top, bottom := triangleHeight*float64(row), triangleHeight*float64(row+1)
+
+	if g.upright(row, col) {
+		return []Side{
+			{Point{left, bottom}, Point{mid, top}, g.cell(row, col-1)},
+			{Point{mid, top}, Point{right, bottom}, g.cell(row, col+1)},
+			{Point{right, bottom}, Point{left, bottom}, g.cell(row+1, col)},
+		}
+	}
+	return []Side{
+		{Point{mid, bottom}, Point{left, top}, g.cell(row, col-1)},
+		{Point{right, top}, Point{mid, bottom}, g.cell(row, col+1)},
+		{Point{left, top}, Point{right, top}, g.cell(row-1, col)},
+	}
+}
+
+// Bounds returns the width and height of the grid
+func (g TriangleGrid) Bounds() (float64, float64) {
+	return 0.5*float64(g.Cols) + 0.5, triangleHeight * float64(g.Rows)
+}
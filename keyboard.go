@@ -0,0 +1,73 @@
+// Package maze ... let the user walk the maze with the arrow keys.
+package maze
+
+// KeyHandler is implemented by walkers that want to receive keyboard events
+// from the Controller.
+type KeyHandler interface {
+	HandleKey(k int)
+}
+
+// KeyboardWalker moves the actor one step in the direction of the last arrow
+// key pressed by the user. On levels with diagonal movement the Home, PgUp, End
+// and PgDn keys step diagonally, like on a numeric keypad. The < and > keys climb
+// up and down the stairs.
+type KeyboardWalker struct {
+	actor   *Actor
+	level   *Level
+	pending []Direction // Moves requested since the last NextPosition
+	start   Position
+}
+
+// Initialize sets up the walker state. It is not safe to start walking without initializing first.
+func (walker *KeyboardWalker) Initialize(level *Level, actor *Actor) {
+	walker.actor = actor
+	walker.level = level
+	walker.pending = nil
+	walker.start = actor.CurrPos
+	actor.chooseExit(level)
+	actor.Path = make([]Position, 0)
+}
+
+// HandleKey records the direction of an arrow or stairs key, other keys are ignored.
+func (walker *KeyboardWalker) HandleKey(k int) {
+	switch k {
+	case KBEventUp:
+		walker.pending = append(walker.pending, Direction{xd: 0, yd: -1})
+	case KBEventDown:
+		walker.pending = append(walker.pending, Direction{xd: 0, yd: 1})
+	case KBEventLeft:
+		walker.pending = append(walker.pending, Direction{xd: -1, yd: 0})
+	case KBEventRight:
+		walker.pending = append(walker.pending, Direction{xd: 1, yd: 0})
+	case KBEventUpLeft:
+		walker.pending = append(walker.pending, Direction{xd: -1, yd: -1})
+	case KBEventUpRight:
+		walker.pending = append(walker.pending, Direction{xd: 1, yd: -1})
+	case KBEventDownLeft:
+		walker.pending = append(walker.pending, Direction{xd: -1, yd: 1})
+	case KBEventDownRight:
+		walker.pending = append(walker.pending, Direction{xd: 1, yd: 1})
+	case KBEventStairsUp:
+		walker.pending = append(walker.pending, Direction{zd: 1})
+	case KBEventStairsDown:
+		walker.pending = append(walker.pending, Direction{zd: -1})
+	}
+}
+
+// NextPosition makes the moves requested since the last call. Moves that the level
+// doesn't allow, like into walls, are silently dropped. An actor heading for any
+// exit is done at whichever exit the user walks to.
+func (walker *KeyboardWalker) NextPosition() {
+	for _, dir := range walker.pending {
+		if walker.level.CanStep(walker.actor.CurrPos, dir) {
+			walker.actor.CurrPos = AddDirection(walker.actor.CurrPos, dir)
+			walker.actor.Path = append(walker.actor.Path, walker.actor.CurrPos)
+		}
+		pos := walker.actor.CurrPos
+		if walker.actor.AnyExit && pos != walker.start && walker.level.isExit(pos) {
+			walker.actor.EndPos = pos
+			break
+		}
+	}
+	walker.pending = walker.pending[:0]
+}
@@ -0,0 +1,189 @@
+// Package maze, level file legend
+package maze
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// readLegend reads the legend that follows the map in a level file. Each line
+// describes the actors with the given glyph using key=value settings:
+//
+//	@ to=exit:2 walker=shortestline color=red
+//	& to=5,7
+//
+// The "to" setting is the actor's destination, either an exit (numbered from 1
+// in the order they appear on the map, "exit" alone means the first one) or a
+// row,col position, row,col,floor on levels with several floors. "nearest" sends
+// the actor to the exit closest to it. "walker" names the walker that moves the
+// actor, with it's settings if any (see NewWalker), and "color" is the colour it's
+// drawn with (see ParseColor).
+//
+// Lines starting with "room" mark the rooms of a dungeon with the top left
+// corner and size of the room's floor:
+//
+//	room 3,4 10x5
+//
+// Blank lines and lines starting with "//" are skipped.
+func readLegend(scanner *bufio.Scanner, level *Level) error {
+	for lineNo := level.floors*(level.height+1) + 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if fields[0] == "room" {
+			var room Room
+			if len(fields) != 3 {
+				return fmt.Errorf("line %d: expected room row,col widthxheight", lineNo)
+			}
+			_, err := fmt.Sscanf(fields[1]+" "+fields[2], "%d,%d %dx%d", &room.Top, &room.Left, &room.Width, &room.Height)
+			if err != nil {
+				return fmt.Errorf("line %d: expected room row,col widthxheight, got %q", lineNo, line)
+			}
+			level.Rooms = append(level.Rooms, room)
+			continue
+		}
+		glyph := []rune(fields[0])
+		if len(glyph) != 1 {
+			return fmt.Errorf("line %d: expected an actor glyph, got %q", lineNo, fields[0])
+		}
+		actors := level.actorsWith(glyph[0])
+		if len(actors) == 0 {
+			return fmt.Errorf("line %d: there is no actor %q on the map", lineNo, glyph[0])
+		}
+
+		for _, setting := range fields[1:] {
+			kv := strings.SplitN(setting, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("line %d: expected key=value, got %q", lineNo, setting)
+			}
+			switch kv[0] {
+			case "to":
+				if kv[1] == "nearest" {
+					for _, actor := range actors {
+						actor.AnyExit = true
+					}
+					break
+				}
+				pos, err := level.parseDestination(kv[1])
+				if err != nil {
+					return fmt.Errorf("line %d: %v", lineNo, err)
+				}
+				for _, actor := range actors {
+					actor.EndPos = pos
+					actor.AnyExit = false
+				}
+			case "walker":
+				for _, actor := range actors {
+					walker, err := NewWalker(kv[1])
+					if err != nil {
+						return fmt.Errorf("line %d: %v", lineNo, err)
+					}
+					actor.PathNav = walker
+				}
+			case "color":
+				color, err := ParseColor(kv[1])
+				if err != nil {
+					return fmt.Errorf("line %d: %v", lineNo, err)
+				}
+				for _, actor := range actors {
+					actor.Color = color
+				}
+			default:
+				return fmt.Errorf("line %d: unknown setting %q", lineNo, kv[0])
+			}
+		}
+	}
+	return nil
+}
+
+// parseDestination parses the "to" setting of the legend
+func (level *Level) parseDestination(s string) (Position, error) {
+	if s == "exit" {
+		s = "exit:1"
+	}
+	if strings.HasPrefix(s, "exit:") {
+		n, err := strconv.Atoi(strings.TrimPrefix(s, "exit:"))
+		if err != nil || n < 1 || n > len(level.Exits) {
+			return Position{}, fmt.Errorf("no such exit %q, the map has %d exits", s, len(level.Exits))
+		}
+		return level.Exits[n-1], nil
+	}
+
+	var pos Position
+	if _, err := fmt.Sscanf(s, "%d,%d,%d", &pos.row, &pos.col, &pos.floor); err != nil {
+		pos.floor = 0
+		if _, err := fmt.Sscanf(s, "%d,%d", &pos.row, &pos.col); err != nil {
+			return Position{}, fmt.Errorf("bad destination %q, expected exit:N, row,col or row,col,floor", s)
+		}
+	}
+	if !level.WithinBounds(pos) {
+		return Position{}, fmt.Errorf("destination %q is outside the map", s)
+	}
+	return pos, nil
+}
+
+// writeLegend writes the legend for the actors whose settings differ from the
+// defaults assumed by ReadLevel, and the rooms.
+func writeLegend(w io.Writer, level Level) error {
+	var lines []string
+	for _, actor := range level.Actors {
+		var settings []string
+		if actor.AnyExit {
+			settings = append(settings, "to=nearest")
+		} else if len(level.Exits) == 0 || actor.EndPos != level.Exits[0] {
+			to := fmt.Sprintf("%d,%d", actor.EndPos.row, actor.EndPos.col)
+			if actor.EndPos.floor != 0 {
+				to += fmt.Sprintf(",%d", actor.EndPos.floor)
+			}
+			for i, exit := range level.Exits {
+				if exit == actor.EndPos {
+					to = fmt.Sprintf("exit:%d", i+1)
+					break
+				}
+			}
+			settings = append(settings, "to="+to)
+		}
+		if spec := WalkerSpec(actor.PathNav); spec != "" && spec != DefaultWalker {
+			settings = append(settings, "walker="+spec)
+		}
+		if actor.Color != ColorDefault {
+			settings = append(settings, "color="+actor.Color.String())
+		}
+		if len(settings) > 0 {
+			lines = append(lines, fmt.Sprintf("%c %s\n", actor.Character, strings.Join(settings, " ")))
+		}
+	}
+	for _, room := range level.Rooms {
+		lines = append(lines, fmt.Sprintf("room %d,%d %dx%d\n", room.Top, room.Left, room.Width, room.Height))
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// actorsWith returns the actors with the given display character
+func (level *Level) actorsWith(c rune) []*Actor {
+	var actors []*Actor
+	for _, actor := range level.Actors {
+		if actor.Character == c {
+			actors = append(actors, actor)
+		}
+	}
+	return actors
+}
@@ -3,33 +3,53 @@ package maze
 
 import (
 	"bufio"
+	"fmt"
+	"io"
+	"unicode/utf8"
 )
 
 // Position coordinates on the level grid
 type Position struct {
 	row, col int
+	floor    int
 }
 
 // Direction is something we can move towards
 type Direction struct {
 	xd int // Column delta
 	yd int // Row delta
+	zd int // Floor delta
 }
 
 // ValidDirections specifies all the valid directions we can move towards
 var ValidDirections = [4]Direction{
-	{0, 1},  // down
-	{1, 0},  // right
-	{-1, 0}, // left
-	{0, -1}, // up
+	{0, 1, 0},  // down
+	{1, 0, 0},  // right
+	{-1, 0, 0}, // left
+	{0, -1, 0}, // up
 }
 
 // Tile types
 const (
 	EmptyTile = iota
 	WallTile
+	StairsUpTile   // Leads to the same spot on the floor above
+	StairsDownTile // Leads to the same spot on the floor below
+	VoidTile       // Outside the maze, nothing is there
 )
 
+const (
+	// StairsUp is used for drawing stairs that lead up
+	StairsUp = '<'
+	// StairsDown is used for drawing stairs that lead down
+	StairsDown = '>'
+	// Void marks the tiles outside the maze in level files, they're drawn blank
+	Void = '~'
+)
+
+// floorSeparator separates the floors in a level file
+const floorSeparator = "---"
+
 // Tile is a map element on the level
 type Tile struct {
 	tileType  int
@@ -40,56 +60,218 @@ type Tile struct {
 type Level struct {
 	width  int
 	height int
-	tiles  [][]Tile   // Level map[row][col]
+	floors int
+	tiles  TileMap    // Level map, see TileMap
 	Actors []*Actor   // Various moving actors on the level
 	Exits  []Position // Exits on the level
+	Rooms  []Room     // Rooms of a dungeon, see GenerateDungeon
+
+	Movement Movement // Steps the actors can take, orthogonal only by default
 }
 
-// ReadLevel reads a level from ASCII art
-func ReadLevel(scanner *bufio.Scanner) Level {
-	level := Level{}
-	for row := 0; scanner.Scan(); row++ {
-		var tileRow []Tile
-		for col, c := range scanner.Text() {
-			tileType := EmptyTile
-			pos := Position{row: row, col: col}
-
-			switch c {
-			case '@', '?', '!', '&':
-				level.Actors = append(level.Actors, &Actor{Character: c, CurrPos: pos})
-				c = ' '
-			case '=':
-				level.Exits = append(level.Exits, pos)
-			case '#':
-				tileType = WallTile
+// noDestination is the destination of the actors read from a level file until they
+// get one, from the exits or the legend
+var noDestination = Position{row: -1, col: -1}
+
+// ReadLevel reads a level from ASCII art. The map ends at the first blank line,
+// anything after that is the legend (see readLegend). Levels with several floors
+// have the floors separated by a "---" line, starting from the ground floor. Rows
+// shorter than the widest row are padded with empty tiles, but the floors must all
+// have the same number of rows.
+//
+// The tiles are kept in the storage. The map is read whole before the storage is
+// made, the size of the level isn't known until then, but that takes only the
+// text of the map on top of the storage.
+//
+// The actors are ready to walk: unless the legend says otherwise they head for
+// the first exit using the DefaultWalker. An actor with nowhere to go, on a level
+// without exits and with no destination in the legend, is an error.
+func ReadLevel(scanner *bufio.Scanner, storage Storage) (Level, error) {
+	level := Level{floors: 1}
+	floors := [][]string{nil}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if line == floorSeparator {
+			level.floors++
+			floors = append(floors, nil)
+			continue
+		}
+		floors[level.floors-1] = append(floors[level.floors-1], line)
+		if width := utf8.RuneCountInString(line); width > level.width {
+			level.width = width
+		}
+	}
+	level.height = len(floors[0])
+	for floor, lines := range floors {
+		if len(lines) != level.height {
+			return level, fmt.Errorf("floor %d has %d rows, the ground floor has %d", floor, len(lines), level.height)
+		}
+	}
+
+	level.tiles = storage.newTiles(level.width, level.height, level.floors, Tile{EmptyTile, ' '})
+	for floor, lines := range floors {
+		for row, line := range lines {
+			col := 0
+			for _, c := range line {
+				tileType := EmptyTile
+				pos := Position{row: row, col: col, floor: floor}
+
+				switch c {
+				case '@', '?', '!', '&':
+					level.Actors = append(level.Actors, &Actor{Character: c, CurrPos: pos, PathNav: &ShortestPathWalker{}})
+					c = ' '
+				case '=':
+					level.Exits = append(level.Exits, pos)
+				case '#', WallBlock:
+					tileType = WallTile
+					c = WallBlock
+				case StairsUp:
+					tileType = StairsUpTile
+				case StairsDown:
+					tileType = StairsDownTile
+				case Void:
+					tileType = VoidTile
+					c = ' '
+				}
+				level.setTile(pos, Tile{tileType: tileType, Character: c})
+				col++
 			}
-			tileRow = append(tileRow, Tile{tileType: tileType, Character: c})
 		}
-		level.tiles = append(level.tiles, tileRow)
-		if row == 0 {
-			level.width = len(tileRow)
+		// Done with the text of the floor
+		floors[floor] = nil
+	}
+
+	for _, actor := range level.Actors {
+		actor.EndPos = noDestination
+		if len(level.Exits) > 0 {
+			actor.EndPos = level.Exits[0]
 		}
-		level.height++
 	}
-	return level
+
+	if err := readLegend(scanner, &level); err != nil {
+		return level, err
+	}
+	for _, actor := range level.Actors {
+		if actor.AnyExit && len(level.Exits) == 0 || !actor.AnyExit && actor.EndPos == noDestination {
+			return level, fmt.Errorf("actor %c has no destination", actor.Character)
+		}
+	}
+	return level, scanner.Err()
+}
+
+// WriteLevel writes the level as ASCII art that can be read back with ReadLevel.
+// Walls are written as '#', exits as '=', void as '~' and actors with their display
+// character. Fails on chunked levels, they're too large to write out.
+func WriteLevel(w io.Writer, level Level) error {
+	if level.Chunked() {
+		return errChunked
+	}
+	glyphs := make(map[Position]rune)
+	for _, pos := range level.Exits {
+		glyphs[pos] = '='
+	}
+	for _, actor := range level.Actors {
+		glyphs[actor.CurrPos] = actor.Character
+	}
+
+	out := bufio.NewWriter(w)
+	for floor := 0; floor < level.floors; floor++ {
+		if floor > 0 {
+			out.WriteString(floorSeparator + "\n")
+		}
+		for row := 0; row < level.height; row++ {
+			for col := 0; col < level.width; col++ {
+				pos := Position{row: row, col: col, floor: floor}
+				c := ' '
+				switch level.tile(pos).tileType {
+				case WallTile:
+					c = '#'
+				case StairsUpTile:
+					c = StairsUp
+				case StairsDownTile:
+					c = StairsDown
+				case VoidTile:
+					c = Void
+				}
+				if g, ok := glyphs[pos]; ok {
+					c = g
+				}
+				out.WriteRune(c)
+			}
+			out.WriteRune('\n')
+		}
+	}
+	if err := writeLegend(out, level); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// Size returns the width and height of the level
+func (level Level) Size() (int, int) {
+	return level.width, level.height
+}
+
+// Floors returns the number of floors on the level
+func (level Level) Floors() int {
+	return level.floors
+}
+
+// Floor returns the floor of the position, 0 being the ground floor
+func (pos Position) Floor() int {
+	return pos.floor
+}
+
+// tile returns the tile at the position, which must be within bounds
+func (level Level) tile(pos Position) Tile {
+	return level.tiles.Tile(pos)
+}
+
+// setTile replaces the tile at the position, which must be within bounds
+func (level *Level) setTile(pos Position, t Tile) {
+	level.tiles.SetTile(pos, t)
+}
+
+// forEachTile calls f for every tile on the level, floor by floor, row by row
+func (level Level) forEachTile(f func(pos Position, t Tile)) {
+	for floor := 0; floor < level.floors; floor++ {
+		for row := 0; row < level.height; row++ {
+			for col := 0; col < level.width; col++ {
+				pos := Position{row: row, col: col, floor: floor}
+				f(pos, level.tile(pos))
+			}
+		}
+	}
+}
+
+// index returns a number for the position that is unique on the level, for keeping
+// track of positions in a slice rather than a map. The position must be within bounds.
+func (level Level) index(pos Position) int {
+	return (pos.floor*level.height+pos.row)*level.width + pos.col
 }
 
 // WithinBounds checks if the position is on the level
 func (level Level) WithinBounds(pos Position) bool {
-	return pos.col >= 0 && pos.row >= 0 && pos.col < level.width && pos.row < level.height
+	return pos.col >= 0 && pos.row >= 0 && pos.col < level.width && pos.row < level.height &&
+		pos.floor >= 0 && pos.floor < level.floors
 }
 
 // WithinFrame checks if the position is within the level frame
 func (level Level) WithinFrame(pos Position) bool {
-	return pos.row > 0 && pos.col > 0 && pos.row < level.height-1 && pos.col < level.width-1
+	return pos.row > 0 && pos.col > 0 && pos.row < level.height-1 && pos.col < level.width-1 &&
+		pos.floor >= 0 && pos.floor < level.floors
 }
 
 // IsWalkable checks if we can walk on this position
 func (level Level) IsWalkable(pos Position) bool {
-	if t := level.tiles[pos.row][pos.col]; t.tileType != EmptyTile {
-		return false
+	switch level.tile(pos).tileType {
+	case EmptyTile, StairsUpTile, StairsDownTile:
+		return true
 	}
-	return true
+	return false
 }
 
 // HasActor tells if there's an actor at a given position or not
@@ -122,7 +304,7 @@ func (level Level) CanMove(pos Position) bool {
 
 // AddDirection adds the direction to position and returns the new position
 func AddDirection(pos Position, d Direction) Position {
-	return Position{row: pos.row + d.yd, col: pos.col + d.xd}
+	return Position{row: pos.row + d.yd, col: pos.col + d.xd, floor: pos.floor + d.zd}
 }
 
 // AddActor adds a new Actor to the level
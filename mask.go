@@ -0,0 +1,155 @@
+// Package maze, masks for generating mazes in arbitrary shapes
+package maze
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Mask marks the tiles that a maze is generated in. Masks are measured in level
+// tiles, without the frame around the level.
+type Mask struct {
+	width, height int
+	on            []bool
+}
+
+// NewMask creates a mask of the given size with all tiles masked out
+func NewMask(width, height int) *Mask {
+	return &Mask{width: width, height: height, on: make([]bool, width*height)}
+}
+
+// Size returns the width and height of the mask
+func (m *Mask) Size() (int, int) {
+	return m.width, m.height
+}
+
+// Set sets whether the tile is part of the maze
+func (m *Mask) Set(row, col int, on bool) {
+	if row >= 0 && col >= 0 && row < m.height && col < m.width {
+		m.on[row*m.width+col] = on
+	}
+}
+
+// On tells if the tile is part of the maze, tiles outside the mask are not
+func (m *Mask) On(row, col int) bool {
+	if row < 0 || col < 0 || row >= m.height || col >= m.width {
+		return false
+	}
+	return m.on[row*m.width+col]
+}
+
+// Scale returns the mask stretched or shrunk to the given size
+func (m *Mask) Scale(width, height int) *Mask {
+	scaled := NewMask(width, height)
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			scaled.Set(row, col, m.On(row*m.height/height, col*m.width/width))
+		}
+	}
+	return scaled
+}
+
+// CircleMask creates a mask of the ellipse that fits the given size
+func CircleMask(width, height int) *Mask {
+	m := NewMask(width, height)
+	rx, ry := float64(width)/2, float64(height)/2
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			x := (float64(col) + 0.5 - rx) / rx
+			y := (float64(row) + 0.5 - ry) / ry
+			m.Set(row, col, x*x+y*y <= 1)
+		}
+	}
+	return m
+}
+
+// TextMask creates a mask of the text written in a blocky 5x7 font. Each dot of the
+// font is scale rows high and twice as many columns wide, as characters on the
+// terminal are about twice as tall as they are wide. The letters don't touch, so
+// each of them gets a maze of it's own.
+func TextMask(text string, scale int) *Mask {
+	text = strings.ToUpper(text)
+	letters := []rune(text)
+	if len(letters) == 0 {
+		return NewMask(0, 0)
+	}
+	dotWidth := 2 * scale
+	m := NewMask((len(letters)*(fontWidth+1)-1)*dotWidth, fontHeight*scale)
+	for i, c := range letters {
+		glyph, ok := font[c]
+		if !ok && !unicode.IsSpace(c) {
+			glyph = font['?']
+		}
+		for y, line := range glyph {
+			for x, dot := range line {
+				if dot == ' ' {
+					continue
+				}
+				for row := y * scale; row < (y+1)*scale; row++ {
+					for col := 0; col < dotWidth; col++ {
+						m.Set(row, (i*(fontWidth+1)+x)*dotWidth+col, true)
+					}
+				}
+			}
+		}
+	}
+	return m
+}
+
+// ReadMask reads a mask from ASCII art, spaces and dots are masked out and any
+// other character is part of the maze. Rows shorter than the widest row are
+// padded with masked out tiles.
+func ReadMask(r io.Reader) (*Mask, error) {
+	var lines []string
+	width := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := []rune(strings.TrimRight(scanner.Text(), " "))
+		lines = append(lines, string(line))
+		if len(line) > width {
+			width = len(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	m := NewMask(width, len(lines))
+	for row, line := range lines {
+		col := 0
+		for _, c := range line {
+			m.Set(row, col, c != ' ' && c != '.')
+			col++
+		}
+	}
+	return m, nil
+}
+
+// ReadPNGMask reads a mask from a PNG image, one pixel per tile. Dark opaque pixels
+// are part of the maze, light or transparent ones are masked out.
+func ReadPNGMask(r io.Reader) (*Mask, error) {
+	img, err := png.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("bad mask image: %v", err)
+	}
+	return imageMask(img), nil
+}
+
+func imageMask(img image.Image) *Mask {
+	bounds := img.Bounds()
+	m := NewMask(bounds.Dx(), bounds.Dy())
+	for row := 0; row < bounds.Dy(); row++ {
+		for col := 0; col < bounds.Dx(); col++ {
+			r, g, b, a := img.At(bounds.Min.X+col, bounds.Min.Y+row).RGBA()
+			// Colour values are premultiplied by alpha, compare the darkness to the opacity
+			light := (299*r + 587*g + 114*b) / 1000
+			m.Set(row, col, a >= 0x8000 && light < a/2)
+		}
+	}
+	return m
+}
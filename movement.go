@@ -0,0 +1,124 @@
+// Package maze, the rules of moving around on the level
+package maze
+
+import (
+	"fmt"
+)
+
+// DiagonalDirections are the moves allowed on top of ValidDirections when the
+// level allows diagonal movement
+var DiagonalDirections = [4]Direction{
+	{1, 1, 0},   // down right
+	{-1, 1, 0},  // down left
+	{1, -1, 0},  // up right
+	{-1, -1, 0}, // up left
+}
+
+// StairDirections are the moves between floors, possible only on the stairs
+var StairDirections = [2]Direction{
+	{0, 0, 1},  // upstairs
+	{0, 0, -1}, // downstairs
+}
+
+// CornerRule decides if a diagonal step may cut past the corner of a wall
+type CornerRule int
+
+// Corner rules
+const (
+	// NoCornerCutting allows diagonal steps only if both of the tiles next to the
+	// step are walkable
+	NoCornerCutting CornerRule = iota
+	// CutCorners allows a diagonal step past one wall
+	CutCorners
+	// SqueezeThrough allows a diagonal step even between two walls
+	SqueezeThrough
+)
+
+var cornerRuleNames = []string{"none", "cut", "squeeze"}
+
+// ParseCornerRule returns the corner rule with the given name: none, cut or squeeze
+func ParseCornerRule(name string) (CornerRule, error) {
+	for i, n := range cornerRuleNames {
+		if n == name {
+			return CornerRule(i), nil
+		}
+	}
+	return NoCornerCutting, fmt.Errorf("unknown corner rule %q", name)
+}
+
+// Movement decides which steps actors can take on the level. The zero value allows
+// only the orthogonal steps.
+type Movement struct {
+	Diagonal bool       // Allow diagonal steps
+	Corners  CornerRule // Which diagonal steps may pass a wall corner
+}
+
+// Costs of a step used for finding the shortest path. A diagonal step is about
+// sqrt(2) times as long as an orthogonal one, which makes for octile distances.
+const (
+	orthogonalCost = 10
+	diagonalCost   = 14
+)
+
+var (
+	orthogonalMoves      = ValidDirections[:]
+	orthogonalStairMoves = append(append([]Direction{}, orthogonalMoves...), StairDirections[:]...)
+	allMoves             = append(append([]Direction{}, ValidDirections[:]...), DiagonalDirections[:]...)
+	allStairMoves        = append(append([]Direction{}, allMoves...), StairDirections[:]...)
+)
+
+// Directions returns the directions actors can step towards on the level. The
+// orthogonal directions come first, in the order of ValidDirections, then the
+// diagonal ones and last the stairs if the level has several floors.
+func (level Level) Directions() []Direction {
+	switch {
+	case level.Movement.Diagonal && level.floors > 1:
+		return allStairMoves
+	case level.Movement.Diagonal:
+		return allMoves
+	case level.floors > 1:
+		return orthogonalStairMoves
+	}
+	return orthogonalMoves
+}
+
+// CanStep tells if an actor at pos can take a step in the direction, considering
+// the movement rules of the level.
+func (level Level) CanStep(pos Position, dir Direction) bool {
+	if !level.CanMove(AddDirection(pos, dir)) {
+		return false
+	}
+	if dir.zd != 0 {
+		stairs := level.tile(pos).tileType
+		return (dir.zd > 0 && stairs == StairsUpTile) || (dir.zd < 0 && stairs == StairsDownTile)
+	}
+	if dir.xd == 0 || dir.yd == 0 {
+		return true
+	}
+	if !level.Movement.Diagonal {
+		return false
+	}
+
+	walls := 0
+	if !level.CanMove(AddDirection(pos, Direction{xd: dir.xd})) {
+		walls++
+	}
+	if !level.CanMove(AddDirection(pos, Direction{yd: dir.yd})) {
+		walls++
+	}
+	switch level.Movement.Corners {
+	case CutCorners:
+		return walls < 2
+	case SqueezeThrough:
+		return true
+	}
+	return walls == 0
+}
+
+// stepCost returns the cost of a step in the direction
+func stepCost(dir Direction) int {
+	if dir.xd != 0 && dir.yd != 0 {
+		return diagonalCost
+	}
+	return orthogonalCost
+}
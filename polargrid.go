@@ -0,0 +1,143 @@
+// Package maze, circular grids
+package maze
+
+import (
+	"math"
+)
+
+// PolarGrid is a circular grid of concentric rings around a single center cell. Each
+// ring is one unit wide and is split into as many cells as fit it without making the
+// cells much wider than they are deep, so the outer rings have more cells.
+type PolarGrid struct {
+	counts  []int // Number of cells on each ring
+	offsets []int // Number of the first cell of each ring
+}
+
+// NewPolarGrid creates a grid with the given number of rings, the center cell included
+func NewPolarGrid(rings int) *PolarGrid {
+	g := PolarGrid{}
+	total := 0
+	for ring := 0; ring < rings; ring++ {
+		count := 1
+		if ring > 0 {
+			prev := g.counts[ring-1]
+			circumference := 2 * math.Pi * float64(ring)
+			ratio := int(math.Round(circumference / float64(prev)))
+			if ratio < 1 {
+				ratio = 1
+			}
+			count = prev * ratio
+		}
+		g.counts = append(g.counts, count)
+		g.offsets = append(g.offsets, total)
+		total += count
+	}
+	return &g
+}
+
+// Rings returns the number of rings
+func (g *PolarGrid) Rings() int {
+	return len(g.counts)
+}
+
+// Size returns the number of cells
+func (g *PolarGrid) Size() int {
+	if len(g.counts) == 0 {
+		return 0
+	}
+	last := len(g.counts) - 1
+	return g.offsets[last] + g.counts[last]
+}
+
+// locate returns the ring of the cell and its index on the ring
+func (g *PolarGrid) locate(c Cell) (int, int) {
+	ring := len(g.offsets) - 1
+	for ring > 0 && int(c) < g.offsets[ring] {
+		ring--
+	}
+	return ring, int(c) - g.offsets[ring]
+}
+
+func (g *PolarGrid) cell(ring, index int) Cell {
+	count := g.counts[ring]
+	return Cell(g.offsets[ring] + (index%count+count)%count)
+}
+
+// point returns the point at the given radius on the boundary between cells index-1
+// and index of the ring. The center of the grid is in the middle of the bounds.
+func (g *PolarGrid) point(radius float64, ring, index int) Point {
+	return g.arcPoint(radius, ring, index, 0)
+}
+
+// outerSides returns the arc on the outside of the cell, split into a side for each of
+// the cells on the next ring that it borders. The arcs are approximated with straight
+// lines.
+func (g *PolarGrid) outerSides(ring, index int) []Side {
+	radius := float64(ring + 1)
+	if ring+1 == len(g.counts) {
+		// The outer edge of the grid, split it into a few pieces so that it looks round
+		pieces := int(math.Ceil(2 * math.Pi * radius / float64(g.counts[ring])))
+		var sides []Side
+		for i := 0; i < pieces; i++ {
+			from := g.arcPoint(radius, ring, index, float64(i)/float64(pieces))
+			to := g.arcPoint(radius, ring, index, float64(i+1)/float64(pieces))
+			sides = append(sides, Side{from, to, NoCell})
+		}
+		return sides
+	}
+
+	ratio := g.counts[ring+1] / g.counts[ring]
+	var sides []Side
+	for child := index * ratio; child < (index+1)*ratio; child++ {
+		sides = append(sides, Side{
+			From:     g.point(radius, ring+1, child),
+			To:       g.point(radius, ring+1, child+1),
+			Neighbor: g.cell(ring+1, child),
+		})
+	}
+	return sides
+}
+
+// arcPoint returns a point on the arc of the cell at the given radius, fraction 0 is
+// the counter clockwise end of the arc and 1 the clockwise end.
+func (g *PolarGrid) arcPoint(radius float64, ring, index int, fraction float64) Point {
+	angle := 2 * math.Pi * (float64(index) + fraction) / float64(g.counts[ring])
+	r := float64(len(g.counts))
+	return Point{r + radius*math.Cos(angle), r + radius*math.Sin(angle)}
+}
+
+// Sides returns the walls of the cell: the inner arc, the clockwise side, the outer
+// arc split between the cells outside and the counter clockwise side.
+func (g *PolarGrid) Sides(c Cell) []Side {
+	ring, index := g.locate(c)
+	if ring == 0 {
+		return g.outerSides(0, 0)
+	}
+
+	inner, outer := float64(ring), float64(ring+1)
+	parent := index / (g.counts[ring] / g.counts[ring-1])
+
+	sides := []Side{
+		{g.point(inner, ring, index+1), g.point(inner, ring, index), g.cell(ring-1, parent)},
+		{g.point(inner, ring, index), g.point(outer, ring, index), g.cell(ring, index-1)},
+	}
+	sides = append(sides, g.outerSides(ring, index)...)
+	sides = append(sides, Side{g.point(outer, ring, index+1), g.point(inner, ring, index+1), g.cell(ring, index+1)})
+	return sides
+}
+
+// Center returns the middle of the cell
+func (g *PolarGrid) Center(c Cell) Point {
+	ring, index := g.locate(c)
+	if ring == 0 {
+		r := float64(len(g.counts))
+		return Point{r, r}
+	}
+	return g.arcPoint(float64(ring)+0.5, ring, index, 0.5)
+}
+
+// Bounds returns the width and height of the grid
+func (g *PolarGrid) Bounds() (float64, float64) {
+	d := 2 * float64(len(g.counts))
+	return d, d
+}
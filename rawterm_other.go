@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+// Package maze, raw mode isn't supported elsewhere than on Linux
+package maze
+
+import "errors"
+
+var errNoRawMode = errors.New("raw terminal mode is only supported on Linux")
+
+// makeRaw fails, the keys are read as the terminal passes them on
+func makeRaw(fd int) (func(), error) {
+	return nil, errNoRawMode
+}
+
+// windowSize fails, the size comes from the environment instead
+func windowSize(fd int) (int, int, error) {
+	return 0, 0, errNoRawMode
+}
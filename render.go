@@ -2,6 +2,9 @@ package maze
 
 import (
 	"fmt"
+	"io"
+	"os"
+
 	"github.com/nsf/termbox-go"
 )
 
@@ -16,9 +19,75 @@ const (
 	KBEventCancel
 	// KBEventPause -- user has paused the rendering (^S or break)
 	KBEventPause
+	// KBEventUp -- arrow up
+	KBEventUp
+	// KBEventDown -- arrow down
+	KBEventDown
+	// KBEventLeft -- arrow left
+	KBEventLeft
+	// KBEventRight -- arrow right
+	KBEventRight
+	// KBEventUpLeft -- home
+	KBEventUpLeft
+	// KBEventUpRight -- page up
+	KBEventUpRight
+	// KBEventDownLeft -- end
+	KBEventDownLeft
+	// KBEventDownRight -- page down
+	KBEventDownRight
+	// KBEventStairsUp -- '<', climb up the stairs
+	KBEventStairsUp
+	// KBEventStairsDown -- '>', go down the stairs
+	KBEventStairsDown
+	// KBEventNextActor -- tab, select the next actor
+	KBEventNextActor
+	// KBEventFloors -- 'f', toggle between showing all floors and the selected actor's floor
+	KBEventFloors
+	// KBEventHeatmap -- 'h', toggle between showing the paths and the heatmap of the visits
+	KBEventHeatmap
+	// KBEventStatus -- 's', toggle the status panel of the actors
+	KBEventStatus
+)
+
+// Color is a display colour. The zero value is the terminal's default colour,
+// the rest follow the order of the basic ANSI colours.
+type Color int
+
+// Colors
+const (
+	ColorDefault Color = iota
+	ColorBlack
+	ColorRed
+	ColorGreen
+	ColorYellow
+	ColorBlue
+	ColorMagenta
+	ColorCyan
+	ColorWhite
 )
 
+var colorNames = []string{"default", "black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// ParseColor returns the colour with the given name, eg. "red"
+func ParseColor(name string) (Color, error) {
+	for i, n := range colorNames {
+		if n == name {
+			return Color(i), nil
+		}
+	}
+	return ColorDefault, fmt.Errorf("unknown color %q", name)
+}
+
+// String returns the name of the colour
+func (c Color) String() string {
+	if c >= 0 && int(c) < len(colorNames) {
+		return colorNames[c]
+	}
+	return fmt.Sprintf("Color(%d)", int(c))
+}
+
 // KeyboardEventChannel is used for passing keyboard events from the renderer to it's client.
+// A renderer reading the keys from a stream closes it when the stream ends.
 type KeyboardEventChannel chan int
 
 // Renderer is an interface that has knows how to display a maze on a terminal
@@ -26,6 +95,7 @@ type KeyboardEventChannel chan int
 // maze can be rendered into a file if needed.
 type Renderer interface {
 	Reset()
+	SetColor(fg, bg Color)
 	PutChar(c rune)
 	GetKeyboardEvent() KeyboardEventChannel
 	NextLine()
@@ -34,18 +104,108 @@ type Renderer interface {
 	Size() (int, int)
 }
 
-// Render draws the level and the path through it
-func Render(level Level, banner string, r Renderer) {
-	// Map out actors and their paths for quick lookup
-	actorMap := make(map[Position]rune)
+// Render draws the level and the path through it. The floors of the level are
+// drawn side by side. Fails on chunked levels, they're too large to draw whole.
+func Render(level Level, banner string, r Renderer) error {
+	if level.Chunked() {
+		return errChunked
+	}
+	renderFloors(level, level.allFloors(), level.wholeView(), banner, r, nil, nil)
+	return nil
+}
+
+// allFloors returns the numbers of all the floors of the level
+func (level Level) allFloors() []int {
+	floors := make([]int, level.floors)
+	for floor := range floors {
+		floors[floor] = floor
+	}
+	return floors
+}
+
+// wholeView returns a view of the whole level
+func (level Level) wholeView() view {
+	return view{0, 0, level.height, level.width}
+}
+
+// RenderFloor draws just one floor of the level. Fails on chunked levels, like Render.
+func RenderFloor(level Level, floor int, banner string, r Renderer) error {
+	if level.Chunked() {
+		return errChunked
+	}
+	renderFloors(level, []int{floor}, level.wholeView(), banner, r, nil, nil)
+	return nil
+}
+
+// RenderAround draws as much of the floor as fits the renderer, with the position
+// in the middle. For levels too large to draw whole.
+func RenderAround(level Level, center Position, banner string, r Renderer) {
+	renderFloors(level, []int{center.floor}, level.viewAround(center, r), banner, r, nil, nil)
+}
+
+// viewAround returns the view of as much of the level as fits the renderer, with the
+// position in the middle
+func (level Level) viewAround(center Position, r Renderer) view {
+	width, height := r.Size()
+	return level.viewAroundIn(center, width, height, wallStyleOf(r))
+}
+
+// viewAroundIn returns the view of as much of the level as fits the width and height
+// in characters, with the position in the middle
+func (level Level) viewAroundIn(center Position, width, height int, style WallStyle) view {
+	v := view{rows: (height - 1) * style.RowsPerLine(), cols: width * style.ColsPerChar()} // Room for the banner
+	v.top = clamp(center.row-v.rows/2, 0, level.height-v.rows)
+	v.left = clamp(center.col-v.cols/2, 0, level.width-v.cols)
+	return v
+}
+
+// view is the part of the level that is drawn
+type view struct {
+	top, left  int
+	rows, cols int
+}
+
+func clamp(x, min, max int) int {
+	if x > max {
+		x = max
+	}
+	if x < min {
+		x = min
+	}
+	return x
+}
+
+// renderFloors draws the floors of the level side by side, the part of them in the
+// view. The overlay is drawn over the tiles instead of the paths of the actors. The
+// lines of the status panel are drawn on the right of the floors if there's room,
+// otherwise below them.
+func renderFloors(level Level, floors []int, v view, banner string, r Renderer, overlay map[Position]glyph, panel []string) {
+	// The heatmap replaces the paths, on the styles that draw a tile per character
+	style := wallStyleOf(r)
+	var heat *Heat
+	if heatmapOf(r) && style.RowsPerLine() == 1 && style.ColsPerChar() == 1 {
+		h := LevelHeat(level)
+		heat = &h
+	}
+
+	// Map out actors and their paths for quick lookup, the overlay counts as paths
+	actorMap := make(map[Position]glyph)
+	for pos, g := range overlay {
+		g.crumb = true
+		actorMap[pos] = g
+	}
 	for _, actor := range level.Actors {
 		for _, pos := range actor.Path {
-			// Avoid overriding actors with breadcrumbs, hence the lookup
-			if _, ok := actorMap[pos]; !ok {
-				actorMap[pos] = '.'
+			if heat != nil || overlay != nil {
+				break
+			}
+			// Avoid overriding actors with breadcrumbs, hence the lookup. Keep
+			// the stairs visible too.
+			if _, ok := actorMap[pos]; !ok && level.tile(pos).tileType == EmptyTile {
+				actorMap[pos] = glyph{c: '.', color: actor.Color, crumb: true}
 			}
 		}
-		actorMap[actor.CurrPos] = actor.Character
+		actorMap[actor.CurrPos] = glyph{c: actor.Character, color: actor.Color}
 	}
 
 	r.Reset()
@@ -55,24 +215,160 @@ func Render(level Level, banner string, r Renderer) {
 	r.NextLine()
 
 	// Display the level, tiles, actors and paths
-	for row, tileRow := range level.tiles {
-		for col, tile := range tileRow {
-			pos := Position{row: row, col: col}
-			c := tile.Character
-			if ac, ok := actorMap[pos]; ok {
-				c = ac
+	bottom := v.top + v.rows // The row below the view
+	if bottom > level.height {
+		bottom = level.height
+	}
+	right := v.left + v.cols
+	if right > level.width {
+		right = level.width
+	}
+	mapWidth := len(floors)*((right-v.left+style.ColsPerChar()-1)/style.ColsPerChar()) + len(floors) - 1
+	sidebar := false
+	if panel != nil {
+		width, _ := r.Size()
+		sidebar = mapWidth+1+StatusWidth <= width
+	}
+	line := 0 // Line of the floors, for the sidebar
+	for row := v.top; row < bottom; row += style.RowsPerLine() {
+		for i, floor := range floors {
+			if i > 0 {
+				r.PutChar(' ')
 			}
-			r.PutChar(c)
+			for col := v.left; col < right; col += style.ColsPerChar() {
+				pos := Position{row: row, col: col, floor: floor}
+				var g glyph
+				if style == WallsHalf {
+					g = halfBlockGlyph(level, pos, row+1 < bottom, actorMap)
+				} else if style == WallsBraille {
+					g = brailleGlyph(level, pos, bottom, right, actorMap)
+				} else if a, ok := actorMap[pos]; ok {
+					g = a
+				} else if h, ok := heat.glyph(pos); ok && level.IsWalkable(pos) {
+					g = h
+				} else if tile := level.tile(pos); tile.tileType == WallTile {
+					g = glyph{c: style.wallGlyph(level, pos)}
+				} else {
+					g = glyph{c: tile.Character}
+				}
+				if g.color != ColorDefault || g.bg != ColorDefault {
+					r.SetColor(g.color, g.bg)
+					r.PutChar(g.c)
+					r.SetColor(ColorDefault, ColorDefault)
+				} else {
+					r.PutChar(g.c)
+				}
+			}
+		}
+		if sidebar && line < len(panel) {
+			r.PutChar(' ')
+			putStatusLine(r, panel[line])
 		}
+		line++
+		r.NextLine()
+	}
+
+	// The rest of the panel below the floors, or all of it if there's no room on the side
+	rest := panel
+	if sidebar && line < len(panel) {
+		rest = panel[line:]
+	} else if sidebar {
+		rest = nil
+	}
+	for _, text := range rest {
+		if sidebar {
+			for i := 0; i <= mapWidth; i++ {
+				r.PutChar(' ')
+			}
+		}
+		putStatusLine(r, text)
 		r.NextLine()
 	}
 	r.NextLine()
 	r.Flush()
 }
 
+// glyph is a character drawn on the level
+type glyph struct {
+	c         rune
+	color, bg Color
+	crumb     bool // A breadcrumb on the path of an actor
+}
+
+// halfBlockGlyph returns the character for the position and the one below it, drawn
+// on the same line. Actors win over breadcrumbs and those over the walls, which are
+// drawn as half blocks.
+func halfBlockGlyph(level Level, pos Position, withBelow bool, actorMap map[Position]glyph) glyph {
+	below := AddDirection(pos, Direction{yd: 1})
+	withBelow = withBelow && level.WithinBounds(below)
+
+	top, topOk := actorMap[pos]
+	bottom, bottomOk := actorMap[below]
+	bottomOk = bottomOk && withBelow
+	switch {
+	case topOk && !top.crumb:
+		return top
+	case bottomOk && !bottom.crumb:
+		return bottom
+	}
+
+	topWall := level.tile(pos).tileType == WallTile
+	bottomWall := withBelow && level.tile(below).tileType == WallTile
+	switch {
+	case topWall && bottomWall:
+		return glyph{c: WallBlock}
+	case topOk:
+		return top
+	case bottomOk:
+		return bottom
+	case topWall:
+		return glyph{c: '▀'}
+	case bottomWall:
+		return glyph{c: '▄'}
+	case level.tile(pos).Character != ' ' || !withBelow:
+		return glyph{c: level.tile(pos).Character}
+	}
+	return glyph{c: level.tile(below).Character}
+}
+
+// brailleGlyph returns the Braille character for the 2x4 tiles starting from the
+// position, with a dot for each wall and breadcrumb. An actor among the tiles is
+// drawn instead, and so are the stairs when there's nothing else.
+func brailleGlyph(level Level, pos Position, bottom, right int, actorMap map[Position]glyph) glyph {
+	// The dots of the Braille characters, [row][col]
+	dots := [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+	g := glyph{c: 0x2800}
+	other := ' ' // Some other character among the tiles
+	for row := pos.row; row < pos.row+4 && row < bottom; row++ {
+		for col := pos.col; col < pos.col+2 && col < right; col++ {
+			at := Position{row: row, col: col, floor: pos.floor}
+			tile := level.tile(at)
+			a, ok := actorMap[at]
+			switch {
+			case ok && !a.crumb:
+				return a
+			case ok:
+				g.c |= dots[row-pos.row][col-pos.col]
+				g.color = a.color
+			case tile.tileType == WallTile:
+				g.c |= dots[row-pos.row][col-pos.col]
+			case tile.Character != ' ':
+				other = tile.Character
+			}
+		}
+	}
+	if g.c == 0x2800 && other != ' ' {
+		return glyph{c: other}
+	}
+	return g
+}
+
 // TermboxRenderer uses the termbox library for rendering the maze.
 type TermboxRenderer struct {
+	renderStyle
 	row, col int
+	fg, bg   Color
 	kbEvents KeyboardEventChannel
 }
 
@@ -94,8 +390,26 @@ func NewTermboxRenderer() *TermboxRenderer {
 					fallthrough
 				case termbox.KeyCtrlC:
 					t.kbEvents <- KBEventCancel
+				case termbox.KeyArrowUp:
+					t.kbEvents <- KBEventUp
+				case termbox.KeyArrowDown:
+					t.kbEvents <- KBEventDown
+				case termbox.KeyArrowLeft:
+					t.kbEvents <- KBEventLeft
+				case termbox.KeyArrowRight:
+					t.kbEvents <- KBEventRight
+				case termbox.KeyHome:
+					t.kbEvents <- KBEventUpLeft
+				case termbox.KeyPgup:
+					t.kbEvents <- KBEventUpRight
+				case termbox.KeyEnd:
+					t.kbEvents <- KBEventDownLeft
+				case termbox.KeyPgdn:
+					t.kbEvents <- KBEventDownRight
+				case termbox.KeyTab:
+					t.kbEvents <- KBEventNextActor
 				default:
-					t.kbEvents <- KBEventUnknown
+					t.kbEvents <- charEvent(ev.Ch)
 				}
 			}
 		}
@@ -104,6 +418,23 @@ func NewTermboxRenderer() *TermboxRenderer {
 	return &t
 }
 
+// charEvent returns the keyboard event of a character key
+func charEvent(ch rune) int {
+	switch ch {
+	case '<':
+		return KBEventStairsUp
+	case '>':
+		return KBEventStairsDown
+	case 'f':
+		return KBEventFloors
+	case 'h':
+		return KBEventHeatmap
+	case 's':
+		return KBEventStatus
+	}
+	return KBEventUnknown
+}
+
 // GetKeyboardEvent returns a channel that can be polled for keyboard events from this renderer.
 func (t *TermboxRenderer) GetKeyboardEvent() KeyboardEventChannel {
 	return t.kbEvents
@@ -128,10 +459,15 @@ func (t *TermboxRenderer) NextLine() {
 // PutChar puts the character into the current position indicated by row and column and
 // advances the column.
 func (t *TermboxRenderer) PutChar(c rune) {
-	termbox.SetCell(t.col, t.row, c, termbox.ColorDefault, termbox.ColorDefault)
+	termbox.SetCell(t.col, t.row, c, termbox.Attribute(t.fg), termbox.Attribute(t.bg))
 	t.col++
 }
 
+// SetColor sets the foreground and background colours for the following characters.
+func (t *TermboxRenderer) SetColor(fg, bg Color) {
+	t.fg, t.bg = fg, bg
+}
+
 // Reset the terminal so that we start again from the top left corner.
 func (t *TermboxRenderer) Reset() {
 	t.col = 0
@@ -143,13 +479,31 @@ func (t *TermboxRenderer) Flush() {
 	termbox.Flush()
 }
 
+// SetCell puts the character on the screen, so that the renderer can be used as
+// the Screen of a DiffRenderer.
+func (t *TermboxRenderer) SetCell(col, row int, c rune, fg, bg Color) {
+	termbox.SetCell(col, row, c, termbox.Attribute(fg), termbox.Attribute(bg))
+}
+
+// Clear blanks the screen.
+func (t *TermboxRenderer) Clear() {
+	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+}
+
 // StreamRenderer renders the maze on an output stream (file, stdout, etc.)
 type StreamRenderer struct {
+	renderStyle
+	out io.Writer
 }
 
-// NewStreamRenderer initializes and returns a new StreamRenderer
+// NewStreamRenderer initializes and returns a new StreamRenderer that writes to stdout
 func NewStreamRenderer() *StreamRenderer {
-	return &StreamRenderer{}
+	return NewStreamRendererTo(os.Stdout)
+}
+
+// NewStreamRendererTo initializes and returns a new StreamRenderer that writes to w
+func NewStreamRendererTo(w io.Writer) *StreamRenderer {
+	return &StreamRenderer{out: w}
 }
 
 // GetKeyboardEvent returns a channel that can be polled for keyboard events from this renderer.
@@ -168,15 +522,18 @@ func (t *StreamRenderer) Size() (int, int) {
 
 // NextLine advances the current row and resets the column to the start of the row.
 func (t *StreamRenderer) NextLine() {
-	fmt.Print("\n")
+	fmt.Fprint(t.out, "\n")
 }
 
 // PutChar puts the character into the current position indicated by row and column and
 // advances the column.
 func (t *StreamRenderer) PutChar(c rune) {
-	fmt.Print(string(c))
+	fmt.Fprint(t.out, string(c))
 }
 
+// SetColor is ignored, the stream is plain text.
+func (t *StreamRenderer) SetColor(fg, bg Color) {}
+
 // Reset the terminal so that we start again from the top left corner.
 func (t *StreamRenderer) Reset() {}
 
@@ -0,0 +1,117 @@
+package maze
+
+import (
+	"bufio"
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// crossing is a level where the actors swap ends
+const crossing = `###=###############
+#@      #         #
+# ##### # ####### #
+# #   # #       # #
+# # # # ####### # #
+# # #           # #
+#   ######### #  &#
+###############=###
+
+@ to=exit:2 walker=shortestline
+& to=exit:1
+`
+
+// twoFloors is a level with the way out on the floor above
+const twoFloors = `#=#####
+#@ <  #
+#######
+---
+#######
+#  >  #
+#####=#
+
+@ to=exit:2
+`
+
+// readTestLevel reads a level from the text
+func readTestLevel(t *testing.T, text string) Level {
+	t.Helper()
+	level, err := ReadLevel(bufio.NewScanner(strings.NewReader(text)), DenseStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return level
+}
+
+// checkGolden compares the frame with the golden file testdata/<name>.golden,
+// go test -update rewrites the file instead
+func checkGolden(t *testing.T, f Frame, name string) {
+	t.Helper()
+	if err := CompareGolden(f, filepath.Join("testdata", name+".golden"), *update); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name  string
+		level string
+		style WallStyle
+	}{
+		{"crossing", crossing, WallsBlock},
+		{"crossing-light", crossing, WallsLight},
+		{"crossing-half", crossing, WallsHalf},
+		{"crossing-braille", crossing, WallsBraille},
+		{"floors", twoFloors, WallsBlock},
+	}
+	for _, test := range tests {
+		r := NewBufferRenderer(80, 24)
+		r.SetWallStyle(test.style)
+		if err := Render(readTestLevel(t, test.level), test.name, r); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		checkGolden(t, r.LastFrame(), "render-"+test.name)
+	}
+}
+
+func TestRenderHeatmap(t *testing.T) {
+	level := readTestLevel(t, crossing)
+	for _, actor := range level.Actors {
+		actor.PathNav.Initialize(&level, actor)
+		for steps := 0; steps < 100 && !actor.HasFinished(); steps++ {
+			actor.Step()
+		}
+	}
+	r := NewBufferRenderer(80, 24)
+	r.SetHeatmap(true)
+	if err := Render(level, "heatmap", r); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, r.LastFrame(), "render-heatmap")
+}
+
+func TestRenderFloor(t *testing.T) {
+	r := NewBufferRenderer(80, 24)
+	if err := RenderFloor(readTestLevel(t, twoFloors), 1, "floor 1", r); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, r.LastFrame(), "render-floor")
+}
+
+func TestRenderRefusesChunked(t *testing.T) {
+	level := NewChunkedLevel(1000, 1000, 1)
+	if err := Render(level, "", NewBufferRenderer(80, 24)); err != errChunked {
+		t.Errorf("got %v, want %v", err, errChunked)
+	}
+}
+
+func TestRenderClipsToSize(t *testing.T) {
+	r := NewBufferRenderer(10, 4)
+	if err := Render(readTestLevel(t, crossing), "clipped", r); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, r.LastFrame(), "render-clipped")
+}
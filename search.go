@@ -0,0 +1,86 @@
+// Package maze, following the search for the shortest path step by step
+package maze
+
+import "strconv"
+
+// SearchEventKind tells what happened in the search
+type SearchEventKind int
+
+// Search events
+const (
+	NodeEnqueued SearchEventKind = iota // The position was reached and queued for expanding
+	NodeExpanded                        // The steps from the position were looked at
+	PathFound                           // The goal was reached
+)
+
+var searchEventNames = []string{"enqueued", "expanded", "found"}
+
+// String returns the name of the event kind
+func (k SearchEventKind) String() string {
+	return searchEventNames[k]
+}
+
+// SearchEvent is one step of a search for the shortest path
+type SearchEvent struct {
+	Kind SearchEventKind
+	Pos  Position
+	Cost int        // Cost of the way from the start to the position, see stepCost
+	Path []Position // The path found, from the goal back to the start like Actor.Path
+}
+
+// SearchTracer is implemented by the walkers that can tell about their search for
+// the path, emit is called for each step of the search
+type SearchTracer interface {
+	TraceSearch(emit func(e SearchEvent))
+}
+
+// TraceSearch searches for the shortest path from one position to another, calling
+// emit for each step of the search. With astar the search is guided towards the
+// goal with A*, otherwise it spreads out evenly like BFS. Returns the path from the
+// goal back to the start, empty if there's no way.
+func TraceSearch(level Level, from, to Position, astar bool, emit func(e SearchEvent)) []Position {
+	var heuristic func(pos Position) int
+	if astar {
+		heuristic = level.estimateCost(to)
+	}
+	return search(level, from, func(pos Position) bool { return pos == to }, heuristic, emit).trace()
+}
+
+// estimateCost returns the A* heuristic for reaching the goal: the cost of the way
+// there if there were no walls. It never overestimates, so A* finds the shortest path.
+func (level Level) estimateCost(goal Position) func(pos Position) int {
+	return func(pos Position) int {
+		rows, cols := abs(pos.row-goal.row), abs(pos.col-goal.col)
+		floors := abs(pos.floor - goal.floor)
+		if !level.Movement.Diagonal {
+			return (rows + cols + floors) * orthogonalCost
+		}
+		if rows > cols {
+			rows, cols = cols, rows
+		}
+		return rows*diagonalCost + (cols-rows+floors)*orthogonalCost
+	}
+}
+
+// AStarWalker walks the shortest path like ShortestPathWalker, but looks for it with
+// A*, which heads for the goal rather than spreading out in every direction. With a
+// weight above 1 the distance to the goal counts for more, which makes the search
+// faster, but the path found may not be the shortest.
+type AStarWalker struct {
+	ShortestPathWalker
+}
+
+// Initialize sets up the walker state. It is not safe to start walking without initializing first.
+func (walker *AStarWalker) Initialize(level *Level, actor *Actor) {
+	walker.astar = true
+	walker.ShortestPathWalker.Initialize(level, actor)
+}
+
+// Params returns the weight of the walker
+func (walker *AStarWalker) Params() map[string]string {
+	weight := walker.weight
+	if weight == 0 {
+		weight = 1
+	}
+	return map[string]string{"weight": strconv.FormatFloat(weight, 'g', -1, 64)}
+}
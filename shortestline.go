@@ -6,7 +6,7 @@ import "math"
 
 // Keeps track of which directions have already been tried
 type visitState struct {
-	tried [4]bool
+	tried [10]bool // Indexed like Level.Directions()
 }
 
 // ShortestLineWalker tries to navigate the maze by always aiming at the shortest
@@ -22,6 +22,7 @@ func (walker *ShortestLineWalker) Initialize(level *Level, actor *Actor) {
 	walker.visitMap = make(map[Position]visitState)
 	walker.actor = actor
 	walker.level = level
+	actor.chooseExit(level)
 	actor.Path = make([]Position, 0)
 }
 
@@ -41,16 +42,19 @@ func (walker *ShortestLineWalker) NextPosition() {
 
 	// Try all valid directions that we have not already visited.
 	// Pick the one that has shortest distance to finish.
-	for index, dir := range ValidDirections {
+	directions := walker.level.Directions()
+	for index, dir := range directions {
 		if visitedDirections.tried[index] {
 			continue
 		}
 
-		// Calculate the euclidean distance from new position to finish
+		// Calculate the euclidean distance from new position to finish. Being on
+		// the wrong floor counts as being a whole level height away.
 		xx := (start.col + dir.xd - finish.col)
 		yy := (start.row + dir.yd - finish.row)
-		dist := int(math.Sqrt(float64(xx*xx + yy*yy)))
-		newPos := Position{row: start.row + dir.yd, col: start.col + dir.xd}
+		zz := (start.floor + dir.zd - finish.floor) * walker.level.height
+		dist := int(math.Sqrt(float64(xx*xx + yy*yy + zz*zz)))
+		newPos := AddDirection(start, dir)
 
 		// We might be stepping on a cell that we've already visited (eg. back
 		// the way we came). Makes sense to consider unexplored cells first, so
@@ -59,7 +63,7 @@ func (walker *ShortestLineWalker) NextPosition() {
 			dist = dist * 1000
 		}
 
-		if dist < shortestLine && walker.level.CanMove(newPos) {
+		if dist < shortestLine && walker.level.CanStep(start, dir) {
 			shortestLine = dist
 			bestDirIndex = index
 		}
@@ -69,7 +73,7 @@ func (walker *ShortestLineWalker) NextPosition() {
 		// We've failed :( This could be because the maze is not navigable or that
 		// we've bumped into another actor.
 	} else {
-		walker.actor.CurrPos = AddDirection(start, ValidDirections[bestDirIndex])
+		walker.actor.CurrPos = AddDirection(start, directions[bestDirIndex])
 		walker.actor.Path = append(walker.actor.Path, walker.actor.CurrPos)
 
 		// Record that we've tried this direction from here
@@ -1,99 +1,186 @@
 // Package maze ... walk the maze using a shortest path.
 package maze
 
+import "container/heap"
+
 // PathNode represents a node on the graph. It's created from an empty space on the map.
 type PathNode struct {
 	parent   *PathNode
-	distance int
+	distance int // Number of steps from the start
+	cost     int // Cost of the steps from the start, see stepCost
+	estimate int // Cost plus the estimated cost of the rest of the way, the order of the search
+	seq      int // Order of discovery, to break ties between equal costs
 	pos      Position
 }
 
+// pathQueue is a priority queue of nodes, cheapest first
+type pathQueue []*PathNode
+
+func (q pathQueue) Len() int { return len(q) }
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].estimate != q[j].estimate {
+		return q[i].estimate < q[j].estimate
+	}
+	return q[i].seq < q[j].seq
+}
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(*PathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
 // CalculateShortestPath generates the shortest path from the current location of the actor to it's
-// endPos using BFS. The level is not mutated in the process, the calculated path is stored in
+// endPos. The level is not mutated in the process, the calculated path is stored in
 // actor.
-//
-// The graph is represented here as a matrix[rows][columns] of nodes where an empty position in the
-// matrix is a node (eg. something that can be walked on) and it's connected to it's neighboring
-// nodes with edges of weight 1.
 func CalculateShortestPath(level Level, actor *Actor, endPos Position) {
-	// First build a map of all the empty tiles marked as unvisited. For convenience
-	// We mark walls as visited, so that we don't consider them as nodes to visit.
-	visitedTiles := make([][]bool, level.height)
-	for row, rowTiles := range level.tiles {
-		visitedTiles[row] = make([]bool, level.width)
-		for col := range rowTiles {
-			if level.tiles[row][col].tileType != EmptyTile {
-				visitedTiles[row][col] = true
-			} else {
-				visitedTiles[row][col] = false
-			}
-		}
-	}
+	finishNode := searchPath(level, actor.CurrPos, func(pos Position) bool { return pos == endPos })
 
-	// PathNode at finish position
-	var finishNode *PathNode
+	// Map the path by tracing back from finish to start.
+	actor.EndPos = endPos
+	actor.Path = finishNode.trace()
+}
 
-	// Queue of nodes that we're going to look at
-	var nodes = []PathNode{
-		PathNode{pos: Position{row: actor.CurrPos.row, col: actor.CurrPos.col}},
+// trace returns the positions from the node back to the start of the search
+func (n *PathNode) trace() []Position {
+	path := make([]Position, 0)
+	for p := n; p != nil; p = p.parent {
+		path = append(path, p.pos)
 	}
+	return path
+}
 
-	// Try stepping onto this node, if OK add it to the end of the queue
-	tryStep := func(sourceNode *PathNode, row, col int) {
-		newPos := Position{row: row, col: col}
-		if !level.CanMove(newPos) || visitedTiles[row][col] {
-			return
-		}
+// searchPath searches the level from start for the cheapest path to the first position
+// that isGoal accepts and returns the node of that position, nil if there's no such
+// position reachable. The returned node can be traced back to start through it's parents.
+//
+// The graph is represented here as a matrix[floors][rows][columns] of nodes where an empty position in the
+// matrix is a node (eg. something that can be walked on) and it's connected to the neighboring
+// nodes that can be reached in one step. Orthogonal steps all cost the same, so on a level
+// without diagonal movement this is BFS. With diagonal steps being more expensive it's Dijkstra.
+func searchPath(level Level, start Position, isGoal func(pos Position) bool) *PathNode {
+	return search(level, start, isGoal, nil, nil)
+}
 
-		destNode := PathNode{
-			distance: sourceNode.distance + 1,
-			pos:      newPos,
+// search is searchPath with the extras: the heuristic estimates the cost from a
+// position to the goal, which makes the search A*, and emit is told what the
+// search is doing. Either can be nil.
+func search(level Level, start Position, isGoal func(pos Position) bool, heuristic func(pos Position) int, emit func(e SearchEvent)) *PathNode {
+	estimate := func(pos Position, cost int) int {
+		if heuristic == nil {
+			return cost
 		}
+		return cost + heuristic(pos)
+	}
+
+	// Keep track of the visited positions and the cheapest known cost of reaching
+	// each position, plus one so that zero means not reached yet. Walls are never
+	// stepped on, so they don't need marking.
+	visitedTiles := newPositionMarks(&level)
+	bestCost := newPositionMarks(&level)
 
-		destNode.distance = sourceNode.distance + 1
-		destNode.parent = sourceNode
-		nodes = append(nodes, destNode)
-		visitedTiles[row][col] = true
+	// Queue of nodes that we're going to look at
+	seq := 0
+	nodes := pathQueue{{pos: start, estimate: estimate(start, 0)}}
+	if emit != nil {
+		emit(SearchEvent{Kind: NodeEnqueued, Pos: start})
 	}
 
 	for len(nodes) > 0 {
-		n := nodes[0]
-		nodes = nodes[1:]
+		n := heap.Pop(&nodes).(*PathNode)
+		if visitedTiles.get(n.pos) != 0 {
+			// Already reached this position more cheaply
+			continue
+		}
+		visitedTiles.set(n.pos, 1)
+		if emit != nil {
+			emit(SearchEvent{Kind: NodeExpanded, Pos: n.pos, Cost: n.cost})
+		}
 
 		// Quit if we're already at finish position
-		if n.pos == endPos {
-			finishNode = &n
-			break
+		if isGoal(n.pos) {
+			if emit != nil {
+				emit(SearchEvent{Kind: PathFound, Pos: n.pos, Cost: n.cost, Path: n.trace()})
+			}
+			return n
 		}
 
-		// Reduce the distances for the nodes neighbors
-		tryStep(&n, n.pos.row, n.pos.col+1)
-		tryStep(&n, n.pos.row+1, n.pos.col)
-		tryStep(&n, n.pos.row, n.pos.col-1)
-		tryStep(&n, n.pos.row-1, n.pos.col)
-	}
-
-	// Map the path by tracing back from finish to start.
-	actor.EndPos = endPos
-	actor.Path = make([]Position, 0)
-	for p := finishNode; p != nil; p = p.parent {
-		actor.Path = append(actor.Path, p.pos)
+		// Try stepping onto the neighbors, queue them if this is the cheapest way there so far
+		for _, dir := range level.Directions() {
+			newPos := AddDirection(n.pos, dir)
+			if !level.CanStep(n.pos, dir) || visitedTiles.get(newPos) != 0 {
+				continue
+			}
+			cost := n.cost + stepCost(dir)
+			if known := bestCost.get(newPos); known > 0 && known <= cost+1 {
+				continue
+			}
+			bestCost.set(newPos, cost+1)
+			seq++
+			heap.Push(&nodes, &PathNode{
+				parent:   n,
+				distance: n.distance + 1,
+				cost:     cost,
+				estimate: estimate(newPos, cost),
+				seq:      seq,
+				pos:      newPos,
+			})
+			if emit != nil {
+				emit(SearchEvent{Kind: NodeEnqueued, Pos: newPos, Cost: cost})
+			}
+		}
 	}
+	return nil
 }
 
 // ShortestPathWalker will navigate the maze using breadth first search
 type ShortestPathWalker struct {
 	actor     *Actor
 	pathIndex int
+	astar     bool              // Search with A* rather than BFS, see AStarWalker
+	weight    float64           // Of the A* heuristic, 1 (or 0) finds the shortest path
+	emit      func(SearchEvent) // Told about the search, see TraceSearch
 }
 
 // Initialize sets up the walker state. It is not safe to start walking without initializing first.
 func (walker *ShortestPathWalker) Initialize(level *Level, actor *Actor) {
 	walker.actor = actor
-	CalculateShortestPath(*level, actor, actor.EndPos)
+	actor.chooseExit(level)
+	end := actor.EndPos
+	var heuristic func(pos Position) int
+	if walker.astar {
+		heuristic = level.estimateCost(end)
+		if weight := walker.weight; weight > 1 {
+			estimate := heuristic
+			heuristic = func(pos Position) int { return int(weight * float64(estimate(pos))) }
+		}
+	}
+	finish := search(*level, actor.CurrPos, func(pos Position) bool { return pos == end }, heuristic, walker.emit)
+	actor.Path = finish.trace()
 	walker.pathIndex = len(actor.Path) - 1
 }
 
+// TraceSearch has the walker tell emit about each step of the search for the path
+// when it's initialized
+func (walker *ShortestPathWalker) TraceSearch(emit func(e SearchEvent)) {
+	walker.emit = emit
+}
+
+// RemainingSteps returns the number of steps left on the path, -1 if there's no path.
+func (walker *ShortestPathWalker) RemainingSteps() int {
+	path := walker.actor.Path
+	if len(path) == 0 {
+		return -1
+	}
+	left := walker.pathIndex + 1
+	if walker.pathIndex >= 0 && path[walker.pathIndex] == walker.actor.CurrPos {
+		left-- // Still at the start
+	}
+	return left
+}
+
 // HasFinished returns true if the walker has reached it's destination
 func (walker *ShortestPathWalker) HasFinished() bool {
 	return walker.actor.CurrPos == walker.actor.EndPos
@@ -0,0 +1,109 @@
+// Package maze, the status panel of the actors on the level
+package maze
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StatusWidth is the width of the status panel in characters, the lines that are
+// longer get cut
+const StatusWidth = 28
+
+// stuckSteps is the number of steps in a row that an actor has to stand still to
+// count as stuck
+const stuckSteps = 5
+
+// PathPlanner is implemented by the walkers that know the path ahead of them
+type PathPlanner interface {
+	RemainingSteps() int // Steps left to the destination, -1 if there's no way
+}
+
+// ActorState tells how an actor is doing
+type ActorState int
+
+// Actor states
+const (
+	ActorRunning  ActorState = iota // On the way
+	ActorFinished                   // At the destination
+	ActorStuck                      // Hasn't moved in a while
+	ActorWaiting                    // Waiting for the user to press keys
+)
+
+var actorStateNames = []string{"running", "finished", "stuck", "waiting"}
+
+// String returns the name of the state
+func (s ActorState) String() string {
+	return actorStateNames[s]
+}
+
+// State tells how the actor is doing. An actor steered by the keyboard doesn't
+// get stuck, it waits for the user.
+func (a *Actor) State() ActorState {
+	switch {
+	case a.HasFinished():
+		return ActorFinished
+	case a.Stalled < stuckSteps:
+		return ActorRunning
+	}
+	if _, ok := a.PathNav.(KeyHandler); ok {
+		return ActorWaiting
+	}
+	return ActorStuck
+}
+
+// statusLines returns the lines of the status panel: the seed (when known) and the
+// size of the level, the corner rule if the actors step diagonally, then two lines
+// for each actor with it's walker, state, position, steps taken and steps left (when
+// the walker knows them).
+func statusLines(level Level, seed int64, hasSeed bool) []string {
+	size := fmt.Sprintf("%dx%d", level.width, level.height)
+	if level.floors > 1 {
+		size = fmt.Sprintf("%s, %d floors", size, level.floors)
+	}
+	lines := []string{size}
+	if hasSeed {
+		lines[0] = fmt.Sprintf("seed %d, %s", seed, size)
+	}
+	if level.Movement.Diagonal {
+		lines = append(lines, "diagonal, corners "+cornerRuleNames[level.Movement.Corners])
+	}
+
+	for _, actor := range level.Actors {
+		name := WalkerSpec(actor.PathNav)
+		if name == "" {
+			name = "?"
+		}
+		left := ""
+		if p, ok := actor.PathNav.(PathPlanner); ok && p.RemainingSteps() >= 0 {
+			left = fmt.Sprintf(", %d left", p.RemainingSteps())
+		}
+		pos := actor.CurrPos
+		at := fmt.Sprintf("%d,%d", pos.row, pos.col)
+		if level.floors > 1 {
+			at = fmt.Sprintf("%s/%d", at, pos.floor)
+		}
+		lines = append(lines,
+			fmt.Sprintf("%c %s %s", actor.Character, name, actor.State()),
+			fmt.Sprintf("  %s %d steps%s", at, actor.Steps, left))
+	}
+
+	for i, line := range lines {
+		if r := []rune(line); len(r) > StatusWidth {
+			lines[i] = string(r[:StatusWidth])
+		}
+	}
+	return lines
+}
+
+// putStatusLine draws a line of the status panel, padded to the full width
+func putStatusLine(r Renderer, line string) {
+	n := 0
+	for _, c := range line {
+		r.PutChar(c)
+		n++
+	}
+	for _, c := range strings.Repeat(" ", StatusWidth-n) {
+		r.PutChar(c)
+	}
+}
@@ -0,0 +1,102 @@
+// Package maze, drawing mazes as SVG images
+package maze
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+const (
+	svgMargin = 10 // Pixels around the maze
+	svgUnit   = 20 // Pixels per unit of a grid maze
+	svgTile   = 10 // Pixels per Level tile
+)
+
+// svgColor returns the SVG name of the colour, with def used for ColorDefault
+func svgColor(c Color, def string) string {
+	if c == ColorDefault {
+		return def
+	}
+	return c.String()
+}
+
+// WriteGridSVG draws the grid maze as an SVG image. The path is drawn in if it's not empty.
+func WriteGridSVG(w io.Writer, m *GridMaze, path []Cell) error {
+	width, height := m.Grid.Bounds()
+	px := func(v float64) float64 { return svgMargin + v*svgUnit }
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\">\n",
+		width*svgUnit+2*svgMargin, height*svgUnit+2*svgMargin)
+	fmt.Fprintf(out, "<rect width=\"100%%\" height=\"100%%\" fill=\"white\"/>\n")
+
+	if len(path) > 0 {
+		fmt.Fprintf(out, "<polyline fill=\"none\" stroke=\"red\" stroke-width=\"%d\" points=\"", svgUnit/5)
+		for _, c := range path {
+			p := m.Grid.Center(c)
+			fmt.Fprintf(out, "%.1f,%.1f ", px(p.X), px(p.Y))
+		}
+		fmt.Fprintf(out, "\"/>\n")
+	}
+
+	fmt.Fprintf(out, "<g stroke=\"black\" stroke-width=\"2\" stroke-linecap=\"round\">\n")
+	forEachWall(m, func(side Side) {
+		fmt.Fprintf(out, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\"/>\n",
+			px(side.From.X), px(side.From.Y), px(side.To.X), px(side.To.Y))
+	})
+	fmt.Fprintf(out, "</g>\n</svg>\n")
+	return out.Flush()
+}
+
+// WriteSVG draws the level as an SVG image with the actors and their paths. The
+// floors of the level are drawn side by side, with the stairs marked in grey.
+func WriteSVG(w io.Writer, level Level) error {
+	if level.Chunked() {
+		return errChunked
+	}
+	px := func(v int) int { return svgMargin + v*svgTile }
+	// Column of the position counting in the floors to the left of it
+	x := func(pos Position) int { return px(pos.floor*(level.width+1) + pos.col) }
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\">\n",
+		(level.floors*(level.width+1)-1)*svgTile+2*svgMargin, level.height*svgTile+2*svgMargin)
+	fmt.Fprintf(out, "<rect width=\"100%%\" height=\"100%%\" fill=\"white\"/>\n")
+
+	fmt.Fprintf(out, "<g fill=\"black\">\n")
+	level.forEachTile(func(pos Position, tile Tile) {
+		switch tile.tileType {
+		case WallTile:
+			fmt.Fprintf(out, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\"/>\n", x(pos), px(pos.row), svgTile, svgTile)
+		case StairsUpTile, StairsDownTile:
+			fmt.Fprintf(out, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"grey\"/>\n", x(pos), px(pos.row), svgTile, svgTile)
+		}
+	})
+	fmt.Fprintf(out, "</g>\n")
+
+	for _, actor := range level.Actors {
+		color := svgColor(actor.Color, "blue")
+		for _, pos := range actor.Path {
+			fmt.Fprintf(out, "<circle cx=\"%d\" cy=\"%d\" r=\"%d\" fill=\"%s\"/>\n",
+				x(pos)+svgTile/2, px(pos.row)+svgTile/2, svgTile/5, color)
+		}
+		fmt.Fprintf(out, "<text x=\"%d\" y=\"%d\" font-size=\"%d\" font-family=\"monospace\" text-anchor=\"middle\" fill=\"%s\">%s</text>\n",
+			x(actor.CurrPos)+svgTile/2, px(actor.CurrPos.row+1)-1, svgTile, color, svgEscape(actor.Character))
+	}
+
+	fmt.Fprintf(out, "</svg>\n")
+	return out.Flush()
+}
+
+func svgEscape(c rune) string {
+	switch c {
+	case '&':
+		return "&amp;"
+	case '<':
+		return "&lt;"
+	case '>':
+		return "&gt;"
+	}
+	return string(c)
+}
@@ -0,0 +1,206 @@
+// Package maze, storage for the tiles of a level
+package maze
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TileMap stores the tiles of a level. The positions given to it are always within
+// the bounds of the level.
+type TileMap interface {
+	Tile(pos Position) Tile
+	SetTile(pos Position, t Tile)
+}
+
+// denseTiles keeps all the tiles of the level in memory, map[floor][row][col]
+type denseTiles [][][]Tile
+
+// newDenseTiles creates the storage for a level filled with the tile
+func newDenseTiles(width, height, floors int, fill Tile) denseTiles {
+	tiles := make(denseTiles, floors)
+	for floor := range tiles {
+		tiles[floor] = make([][]Tile, height)
+		for row := range tiles[floor] {
+			tiles[floor][row] = make([]Tile, width)
+			for col := range tiles[floor][row] {
+				tiles[floor][row][col] = fill
+			}
+		}
+	}
+	return tiles
+}
+
+// Tile returns the tile at the position
+func (tiles denseTiles) Tile(pos Position) Tile {
+	return tiles[pos.floor][pos.row][pos.col]
+}
+
+// SetTile replaces the tile at the position
+func (tiles denseTiles) SetTile(pos Position, t Tile) {
+	tiles[pos.floor][pos.row][pos.col] = t
+}
+
+// packedTiles keeps the tiles of the level in a few bits each: a bit per tile for the
+// walls and, once there's anything else than walls and empty tiles, 4 bits per tile
+// for the tile type. The characters go with the tile type, the odd ones that don't
+// (like the '=' of the exits in a level file) are kept aside. A tile takes a bit
+// instead of the 128 bits of a Tile, or 5 bits on levels with stairs or void.
+type packedTiles struct {
+	width, height int
+	walls         []uint64       // Bit per tile, set for walls
+	terrain       []uint8        // Tile types other than walls, 2 tiles per byte. Nil while there are none.
+	glyphs        map[int64]rune // Characters that don't go with the tile type
+}
+
+// typeGlyphs are the characters of the tile types in packedTiles
+var typeGlyphs = [...]rune{
+	EmptyTile:      ' ',
+	WallTile:       WallBlock,
+	StairsUpTile:   StairsUp,
+	StairsDownTile: StairsDown,
+	VoidTile:       ' ',
+}
+
+// newPackedTiles creates the packed storage for a level filled with the tile
+func newPackedTiles(width, height, floors int, fill Tile) *packedTiles {
+	size := int64(width) * int64(height) * int64(floors)
+	tiles := &packedTiles{width: width, height: height, walls: make([]uint64, (size+63)/64)}
+	if fill != (Tile{}) {
+		for i := int64(0); i < size; i++ {
+			tiles.set(i, fill)
+		}
+	}
+	return tiles
+}
+
+func (tiles *packedTiles) index(pos Position) int64 {
+	return (int64(pos.floor)*int64(tiles.height)+int64(pos.row))*int64(tiles.width) + int64(pos.col)
+}
+
+// Tile returns the tile at the position
+func (tiles *packedTiles) Tile(pos Position) Tile {
+	i := tiles.index(pos)
+	t := Tile{tileType: EmptyTile}
+	if tiles.walls[i/64]&(1<<uint(i%64)) != 0 {
+		t.tileType = WallTile
+	} else if tiles.terrain != nil {
+		t.tileType = int(tiles.terrain[i/2]>>uint(4*(i%2))) & 0xf
+	}
+	t.Character = typeGlyphs[t.tileType]
+	if c, ok := tiles.glyphs[i]; ok {
+		t.Character = c
+	}
+	return t
+}
+
+// SetTile replaces the tile at the position
+func (tiles *packedTiles) SetTile(pos Position, t Tile) {
+	tiles.set(tiles.index(pos), t)
+}
+
+func (tiles *packedTiles) set(i int64, t Tile) {
+	if t.tileType == WallTile {
+		tiles.walls[i/64] |= 1 << uint(i%64)
+	} else {
+		tiles.walls[i/64] &^= 1 << uint(i%64)
+	}
+
+	terrain := t.tileType
+	if terrain == WallTile {
+		terrain = EmptyTile
+	}
+	if terrain < 0 || terrain >= len(typeGlyphs) {
+		panic(fmt.Sprintf("tile type %d can't be packed", t.tileType))
+	}
+	if tiles.terrain == nil && terrain != EmptyTile {
+		tiles.terrain = make([]uint8, (int64(len(tiles.walls))*64+1)/2)
+	}
+	if tiles.terrain != nil {
+		shift := uint(4 * (i % 2))
+		tiles.terrain[i/2] = tiles.terrain[i/2]&^(0xf<<shift) | uint8(terrain)<<shift
+	}
+
+	if t.Character == typeGlyphs[t.tileType] {
+		delete(tiles.glyphs, i)
+	} else {
+		if tiles.glyphs == nil {
+			tiles.glyphs = make(map[int64]rune)
+		}
+		tiles.glyphs[i] = t.Character
+	}
+}
+
+// Storage is how the tiles of a level are kept in memory. The zero value is
+// DenseStorage.
+type Storage int
+
+// Storages of the tiles
+const (
+	DenseStorage  Storage = iota // A Tile for each spot, which is fast
+	PackedStorage                // Packed into bits, 1/128 of the memory or 5/128 with stairs or void
+)
+
+var storageNames = []string{"dense", "packed"}
+
+// ParseStorage returns the storage with the name, "dense" or "packed"
+func ParseStorage(name string) (Storage, error) {
+	for i, n := range storageNames {
+		if n == name {
+			return Storage(i), nil
+		}
+	}
+	return DenseStorage, fmt.Errorf("unknown storage %q, choose one of: %s", name, strings.Join(storageNames, ", "))
+}
+
+// String returns the name of the storage
+func (s Storage) String() string {
+	return storageNames[s]
+}
+
+// StorageNames returns the names of the storages
+func StorageNames() []string {
+	return append([]string{}, storageNames...)
+}
+
+// newTiles creates the storage for a level filled with the tile
+func (s Storage) newTiles(width, height, floors int, fill Tile) TileMap {
+	if s == PackedStorage {
+		return newPackedTiles(width, height, floors, fill)
+	}
+	return newDenseTiles(width, height, floors, fill)
+}
+
+// positionMarks keeps a number for each position of a level, in a slice if the level
+// is small enough and in a map for the huge ones. Unmarked positions are zero.
+type positionMarks struct {
+	level  *Level
+	dense  []int32
+	sparse map[Position]int
+}
+
+// maxDenseMarks is the size of the largest level that gets a slice for the marks
+const maxDenseMarks = 1 << 24
+
+func newPositionMarks(level *Level) positionMarks {
+	size := int64(level.floors) * int64(level.height) * int64(level.width)
+	if size <= maxDenseMarks {
+		return positionMarks{level: level, dense: make([]int32, size)}
+	}
+	return positionMarks{level: level, sparse: make(map[Position]int)}
+}
+
+func (m positionMarks) get(pos Position) int {
+	if m.dense != nil {
+		return int(m.dense[m.level.index(pos)])
+	}
+	return m.sparse[pos]
+}
+
+func (m positionMarks) set(pos Position, v int) {
+	if m.dense != nil {
+		m.dense[m.level.index(pos)] = int32(v)
+	} else {
+		m.sparse[pos] = v
+	}
+}
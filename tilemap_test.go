@@ -0,0 +1,72 @@
+package maze
+
+import (
+	"bufio"
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// benchSize is the width and height of the mazes in the benchmarks
+const benchSize = 500
+
+// generateIn generates the same maze every time, with the tiles in the storage
+func generateIn(tb testing.TB, storage Storage) Level {
+	rand.Seed(1)
+	level, err := GenerateMultiFloorMaze(benchSize, benchSize, 1, storage)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return level
+}
+
+func benchmarkGenerate(b *testing.B, storage Storage) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		generateIn(b, storage)
+	}
+}
+
+func BenchmarkGenerateDense(b *testing.B)  { benchmarkGenerate(b, DenseStorage) }
+func BenchmarkGeneratePacked(b *testing.B) { benchmarkGenerate(b, PackedStorage) }
+
+func benchmarkSearch(b *testing.B, storage Storage) {
+	level := generateIn(b, storage)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		actor := Actor{CurrPos: level.Exits[0]}
+		CalculateShortestPath(level, &actor, level.Exits[1])
+	}
+}
+
+func BenchmarkSearchDense(b *testing.B)  { benchmarkSearch(b, DenseStorage) }
+func BenchmarkSearchPacked(b *testing.B) { benchmarkSearch(b, PackedStorage) }
+
+func TestStoragesHoldTheSameTiles(t *testing.T) {
+	dense, packed := generateIn(t, DenseStorage), generateIn(t, PackedStorage)
+	dense.forEachTile(func(pos Position, want Tile) {
+		if got := packed.tile(pos); got != want {
+			t.Fatalf("packed tile at %d,%d is %v, dense %v", pos.row, pos.col, got, want)
+		}
+	})
+}
+
+func TestReadLevelIntoStorage(t *testing.T) {
+	var text bytes.Buffer
+	if err := WriteLevel(&text, generateIn(t, DenseStorage)); err != nil {
+		t.Fatal(err)
+	}
+	for _, storage := range []Storage{DenseStorage, PackedStorage} {
+		level, err := ReadLevel(bufio.NewScanner(bytes.NewReader(text.Bytes())), storage)
+		if err != nil {
+			t.Fatalf("%s: %v", storage, err)
+		}
+		var again bytes.Buffer
+		if err := WriteLevel(&again, level); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again.Bytes(), text.Bytes()) {
+			t.Errorf("%s: level read back differs from the one written", storage)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Package maze, looking up walkers by name
+package maze
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultWalker is the name of the walker actors get when nothing else is said
+const DefaultWalker = "shortestpath"
+
+// WalkerParam is a setting of a walker, given after the name of the walker (see
+// NewWalker)
+type WalkerParam struct {
+	Name        string
+	Description string
+	Default     string
+}
+
+// WalkerInfo describes a walker in the registry
+type WalkerInfo struct {
+	Name        string
+	Description string
+	Params      []WalkerParam
+	// New creates the walker with the settings, every param has a value
+	New func(params map[string]string) (Walker, error)
+}
+
+// WalkerParams is implemented by the walkers that have settings, to tell the
+// settings they were created with
+type WalkerParams interface {
+	Params() map[string]string
+}
+
+// walkerRegistry maps the walker names used in level files and on the command line
+// to the walkers, see RegisterWalker.
+var walkerRegistry = make(map[string]WalkerInfo)
+
+// walkerTypes maps the types of the registered walkers to their names, for
+// WalkerName. Walkers that share a type go by the name registered first.
+var walkerTypes = make(map[reflect.Type]string)
+
+// builtinWalkers are the walkers of the package, registered on init
+var builtinWalkers = []WalkerInfo{
+	{
+		Name:        "shortestpath",
+		Description: "walks the shortest path, found with breadth first search",
+		New:         func(map[string]string) (Walker, error) { return &ShortestPathWalker{}, nil },
+	},
+	{
+		Name:        "shortestline",
+		Description: "heads for the exit as the crow flies, backtracking from dead ends",
+		New:         func(map[string]string) (Walker, error) { return &ShortestLineWalker{}, nil },
+	},
+	{
+		Name:        "keyboard",
+		Description: "walks where the arrow keys say",
+		New:         func(map[string]string) (Walker, error) { return &KeyboardWalker{}, nil },
+	},
+	{
+		Name:        "astar",
+		Description: "walks the shortest path, found with A*",
+		Params: []WalkerParam{
+			{"weight", "weight of the distance to the exit, above 1 searches less but may miss the shortest path", "1"},
+		},
+		New: func(params map[string]string) (Walker, error) {
+			weight, err := strconv.ParseFloat(params["weight"], 64)
+			if err != nil || weight < 1 {
+				return nil, fmt.Errorf("astar weight must be a number of at least 1, got %q", params["weight"])
+			}
+			walker := &AStarWalker{}
+			walker.weight = weight
+			return walker, nil
+		},
+	},
+	{
+		Name:        "wallfollower",
+		Description: "keeps a hand on the wall, finds the way out of mazes without loops",
+		Params: []WalkerParam{
+			{"hand", "the hand kept on the wall: right or left", "right"},
+		},
+		New: func(params map[string]string) (Walker, error) {
+			switch params["hand"] {
+			case "right":
+				return &WallFollowerWalker{}, nil
+			case "left":
+				return &WallFollowerWalker{leftHand: true}, nil
+			}
+			return nil, fmt.Errorf("wallfollower hand must be right or left, got %q", params["hand"])
+		},
+	},
+}
+
+func init() {
+	for _, info := range builtinWalkers {
+		RegisterWalker(info)
+	}
+}
+
+// RegisterWalker adds a walker to the registry, so that it can be created by name
+// with NewWalker in level files and on the command line. Panics if there's
+// already a walker with the name.
+func RegisterWalker(info WalkerInfo) {
+	if _, ok := walkerRegistry[info.Name]; ok {
+		panic(fmt.Sprintf("Walker %q registered twice!", info.Name))
+	}
+	walkerRegistry[info.Name] = info
+	if walker, err := info.New(info.defaults()); err == nil {
+		if _, ok := walkerTypes[reflect.TypeOf(walker)]; !ok {
+			walkerTypes[reflect.TypeOf(walker)] = info.Name
+		}
+	}
+}
+
+// Walkers returns the registered walkers sorted by name
+func Walkers() []WalkerInfo {
+	var walkers []WalkerInfo
+	for _, name := range WalkerNames() {
+		walkers = append(walkers, walkerRegistry[name])
+	}
+	return walkers
+}
+
+// NewWalker creates a walker from the registry. The spec is the name of the walker,
+// optionally followed by settings that replace the defaults of the walker's
+// params, each after a colon, eg. "wallfollower:hand=left".
+func NewWalker(spec string) (Walker, error) {
+	fields := strings.Split(spec, ":")
+	info, ok := walkerRegistry[fields[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown walker %q, choose one of: %s", fields[0], strings.Join(WalkerNames(), ", "))
+	}
+
+	params := info.defaults()
+	for _, setting := range fields[1:] {
+		kv := strings.SplitN(setting, "=", 2)
+		if _, ok := params[kv[0]]; !ok || len(kv) != 2 {
+			return nil, fmt.Errorf("walker %s has no setting %q, expected one of: %s", info.Name, setting, info.paramNames())
+		}
+		params[kv[0]] = kv[1]
+	}
+	return info.New(params)
+}
+
+// paramNames lists the params of the walker as name=default
+func (info WalkerInfo) paramNames() string {
+	if len(info.Params) == 0 {
+		return "none"
+	}
+	var names []string
+	for _, p := range info.Params {
+		names = append(names, p.Name+"="+p.Default)
+	}
+	return strings.Join(names, ", ")
+}
+
+// WalkerNames returns the sorted names of all the walkers
+func WalkerNames() []string {
+	var names []string
+	for name := range walkerRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WalkerName returns the name of the walker, empty if it's not one of the registered
+// walkers. Walkers of the same type share the name registered first.
+func WalkerName(w Walker) string {
+	return walkerTypes[reflect.TypeOf(w)]
+}
+
+// defaults returns the default settings of the walker's params
+func (info WalkerInfo) defaults() map[string]string {
+	params := make(map[string]string)
+	for _, p := range info.Params {
+		params[p.Name] = p.Default
+	}
+	return params
+}
+
+// WalkerSpec returns the spec that creates the walker with NewWalker: the name and
+// the settings that differ from the defaults. Empty if the walker isn't registered.
+func WalkerSpec(w Walker) string {
+	name := WalkerName(w)
+	withParams, ok := w.(WalkerParams)
+	if name == "" || !ok {
+		return name
+	}
+	spec := name
+	values := withParams.Params()
+	for _, p := range walkerRegistry[name].Params {
+		if v, ok := values[p.Name]; ok && v != p.Default {
+			spec += ":" + p.Name + "=" + v
+		}
+	}
+	return spec
+}
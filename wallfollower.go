@@ -0,0 +1,80 @@
+// Package maze ... follow the wall on the right hand side until the exit turns up.
+package maze
+
+// clockwise are the orthogonal directions in clockwise order, starting from up
+var clockwise = [4]Direction{{0, -1, 0}, {1, 0, 0}, {0, 1, 0}, {-1, 0, 0}}
+
+// WallFollowerWalker keeps it's right hand on the wall and walks on, or the left
+// hand with leftHand. In a maze without loops that always finds the way out sooner
+// or later, but with loops it can go round in circles forever. On a level with
+// several floors it takes any stairs that lead towards the floor of the exit.
+type WallFollowerWalker struct {
+	actor    *Actor
+	level    *Level
+	heading  int // Index to clockwise
+	leftHand bool
+}
+
+// hand returns the turn to the side of the hand on the wall, in steps of clockwise
+func (walker *WallFollowerWalker) hand() int {
+	if walker.leftHand {
+		return 3
+	}
+	return 1
+}
+
+// Params returns the hand the walker keeps on the wall
+func (walker *WallFollowerWalker) Params() map[string]string {
+	if walker.leftHand {
+		return map[string]string{"hand": "left"}
+	}
+	return map[string]string{"hand": "right"}
+}
+
+// Initialize sets up the walker state. It is not safe to start walking without initializing first.
+func (walker *WallFollowerWalker) Initialize(level *Level, actor *Actor) {
+	walker.actor = actor
+	walker.level = level
+	actor.chooseExit(level)
+	actor.Path = make([]Position, 0)
+
+	// Head off in a direction where there's a wall on the side of the hand
+	walker.heading = 0
+	for i := range clockwise {
+		side := AddDirection(actor.CurrPos, clockwise[(i+walker.hand())%4])
+		if level.CanStep(actor.CurrPos, clockwise[i]) && !level.CanMove(side) {
+			walker.heading = i
+			break
+		}
+	}
+}
+
+// NextPosition turns towards the hand on the wall if it can, otherwise goes straight
+// on, turns the other way or as the last resort turns back.
+func (walker *WallFollowerWalker) NextPosition() {
+	pos := walker.actor.CurrPos
+	if end := walker.actor.EndPos; end.floor != pos.floor {
+		stairs := Direction{zd: 1}
+		if end.floor < pos.floor {
+			stairs.zd = -1
+		}
+		if walker.level.CanStep(pos, stairs) {
+			walker.step(stairs)
+			return
+		}
+	}
+
+	for _, turn := range []int{walker.hand(), 0, 4 - walker.hand(), 2} {
+		heading := (walker.heading + turn) % 4
+		if walker.level.CanStep(pos, clockwise[heading]) {
+			walker.heading = heading
+			walker.step(clockwise[heading])
+			return
+		}
+	}
+}
+
+func (walker *WallFollowerWalker) step(dir Direction) {
+	walker.actor.CurrPos = AddDirection(walker.actor.CurrPos, dir)
+	walker.actor.Path = append(walker.actor.Path, walker.actor.CurrPos)
+}
@@ -0,0 +1,150 @@
+// Package maze, the styles walls are drawn in
+package maze
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WallStyle is the way the walls are drawn. The zero value draws every wall as a
+// full block.
+type WallStyle int
+
+// Wall styles
+const (
+	WallsBlock   WallStyle = iota // Full blocks, one per wall
+	WallsLight                    // Light box drawing lines, ┌─┐
+	WallsHeavy                    // Heavy box drawing lines, ┏━┓
+	WallsDouble                   // Double box drawing lines, ╔═╗
+	WallsRounded                  // Light box drawing lines with rounded corners, ╭─╮
+	WallsHalf                     // Half blocks, two rows of the level on each line
+	WallsBraille                  // Braille dots, 2x4 tiles in each character
+)
+
+var wallStyleNames = []string{"block", "light", "heavy", "double", "rounded", "half", "braille"}
+
+// wallGlyphs are the box drawing characters of the styles, indexed by the walls
+// next to the wall being drawn: 1 above, 2 to the right, 4 below and 8 to the left.
+var wallGlyphs = map[WallStyle][16]rune{
+	WallsLight:   {'▪', '╵', '╶', '└', '╷', '│', '┌', '├', '╴', '┘', '─', '┴', '┐', '┤', '┬', '┼'},
+	WallsHeavy:   {'▪', '╹', '╺', '┗', '╻', '┃', '┏', '┣', '╸', '┛', '━', '┻', '┓', '┫', '┳', '╋'},
+	WallsDouble:  {'▪', '║', '═', '╚', '║', '║', '╔', '╠', '═', '╝', '═', '╩', '╗', '╣', '╦', '╬'},
+	WallsRounded: {'▪', '╵', '╶', '╰', '╷', '│', '╭', '├', '╴', '╯', '─', '┴', '╮', '┤', '┬', '┼'},
+}
+
+// ParseWallStyle returns the wall style with the given name, eg. "light"
+func ParseWallStyle(name string) (WallStyle, error) {
+	for i, n := range wallStyleNames {
+		if n == name {
+			return WallStyle(i), nil
+		}
+	}
+	return WallsBlock, fmt.Errorf("unknown wall style %q, choose one of: %s", name, strings.Join(wallStyleNames, ", "))
+}
+
+// WallStyleNames returns the names of the wall styles
+func WallStyleNames() []string {
+	return append([]string(nil), wallStyleNames...)
+}
+
+// String returns the name of the wall style
+func (s WallStyle) String() string {
+	if s >= 0 && int(s) < len(wallStyleNames) {
+		return wallStyleNames[s]
+	}
+	return fmt.Sprintf("WallStyle(%d)", int(s))
+}
+
+// RowsPerLine returns the number of level rows drawn on each line
+func (s WallStyle) RowsPerLine() int {
+	switch s {
+	case WallsHalf:
+		return 2
+	case WallsBraille:
+		return 4
+	}
+	return 1
+}
+
+// ColsPerChar returns the number of level columns drawn in each character
+func (s WallStyle) ColsPerChar() int {
+	if s == WallsBraille {
+		return 2
+	}
+	return 1
+}
+
+// wallGlyph returns the character for the wall at the position. The box drawing
+// styles join the wall with the walls next to it on the same floor.
+func (s WallStyle) wallGlyph(level Level, pos Position) rune {
+	glyphs, ok := wallGlyphs[s]
+	if !ok {
+		return level.tile(pos).Character
+	}
+	joins := 0
+	for i, dir := range []Direction{{0, -1, 0}, {1, 0, 0}, {0, 1, 0}, {-1, 0, 0}} {
+		next := AddDirection(pos, dir)
+		if level.WithinBounds(next) && level.tile(next).tileType == WallTile {
+			joins |= 1 << uint(i)
+		}
+	}
+	return glyphs[joins]
+}
+
+// renderStyle is embedded in the renderers to let the way the level is drawn be chosen
+type renderStyle struct {
+	style   WallStyle
+	heatmap bool
+}
+
+// SetWallStyle chooses the way the walls are drawn
+func (s *renderStyle) SetWallStyle(style WallStyle) {
+	s.style = style
+}
+
+// WallStyle returns the way the walls are drawn
+func (s *renderStyle) WallStyle() WallStyle {
+	return s.style
+}
+
+// SetHeatmap chooses whether the visits of the actors are drawn as a heatmap
+// instead of their paths, see Actor.Visits
+func (s *renderStyle) SetHeatmap(on bool) {
+	s.heatmap = on
+}
+
+// Heatmap tells if the visits of the actors are drawn as a heatmap
+func (s *renderStyle) Heatmap() bool {
+	return s.heatmap
+}
+
+// wallStyleOf returns the wall style of the renderer, full blocks if the renderer
+// doesn't have a choice
+func wallStyleOf(r Renderer) WallStyle {
+	if styled, ok := r.(interface{ WallStyle() WallStyle }); ok {
+		return styled.WallStyle()
+	}
+	return WallsBlock
+}
+
+// heatmapOf tells if the renderer draws the visits of the actors as a heatmap
+func heatmapOf(r Renderer) bool {
+	if heated, ok := r.(interface{ Heatmap() bool }); ok {
+		return heated.Heatmap()
+	}
+	return false
+}
+
+// levelLines returns the number of lines the renderer takes to draw the rows of a
+// level, not counting the banner
+func levelLines(r Renderer, rows int) int {
+	per := wallStyleOf(r).RowsPerLine()
+	return (rows + per - 1) / per
+}
+
+// levelColumns returns the number of characters the renderer takes to draw the
+// columns of a level
+func levelColumns(r Renderer, cols int) int {
+	per := wallStyleOf(r).ColsPerChar()
+	return (cols + per - 1) / per
+}